@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/storage"
+)
+
+// benchCommand compares the store's insert modes by writing synthetic ticks
+// into a scratch copy of the market data table.
+func benchCommand(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	rows := fs.Int("rows", 100000, "ticks to insert per mode")
+	batch := fs.Int("batch", cfg.BatchSize, "ticks per InsertBatch call")
+	modes := fs.String("modes", "row,values,copy", "comma-separated insert modes to run")
+	table := fs.String("table", "market_data_bench", "scratch table, dropped afterwards")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *rows <= 0 || *batch <= 0 {
+		fmt.Fprintln(os.Stderr, "-rows and -batch must be positive")
+		return 2
+	}
+	var list []storage.InsertMode
+	for _, m := range strings.Split(*modes, ",") {
+		mode, err := storage.ParseInsertMode(strings.TrimSpace(m))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		list = append(list, mode)
+	}
+
+	ctx := context.Background()
+	store, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	ticks := syntheticTicks(*rows)
+	fmt.Printf("%-8s %10s %8s %12s %14s\n", "mode", "rows", "batch", "elapsed", "rows/s")
+	for _, mode := range list {
+		scratch, err := store.Scratch(ctx, *table)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "create %s: %v\n", *table, err)
+			return 1
+		}
+		scratch.SetInsertMode(mode)
+
+		start := time.Now()
+		for i := 0; i < len(ticks); i += *batch {
+			if _, err := scratch.InsertBatch(ctx, ticks[i:min(i+*batch, len(ticks))]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", mode, err)
+				scratch.DropScratch(ctx)
+				return 1
+			}
+		}
+		elapsed := time.Since(start)
+		fmt.Printf("%-8s %10d %8d %12s %14.0f\n", mode, len(ticks), *batch, elapsed.Round(time.Millisecond), float64(len(ticks))/elapsed.Seconds())
+
+		if err := scratch.DropScratch(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "drop %s: %v\n", *table, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// syntheticTicks builds n quote+trade ticks spread over the NSE futures list.
+func syntheticTicks(n int) []models.MarketData {
+	symbols := constants.NSE_SYMBOLS
+	base := time.Now().UnixMilli()
+	ticks := make([]models.MarketData, n)
+	for i := range ticks {
+		ltp := 1000 + float64(i%5000)/20
+		ticks[i] = models.MarketData{
+			Name:      symbols[i%len(symbols)],
+			Timestamp: base + int64(i),
+			Exchange:  "nse",
+			Feed:      "bench",
+			Data: map[string]interface{}{
+				"ltp": ltp,
+				"bid": ltp - 0.05,
+				"ask": ltp + 0.05,
+				"qty": float64(1 + i%50),
+			},
+		}
+		ticks[i].Classify()
+	}
+	return ticks
+}
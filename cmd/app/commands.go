@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/services/instruments"
+	"ws_ingestor/internal/app/services/storage"
+)
+
+const usage = `usage: ws_ingestor [command]
+
+Without a command the ingestor service runs.
+
+Commands:
+  instruments import <file.csv|file.json>   upsert instruments into the master
+  instruments list [exchange]               print instruments as JSON
+  deadletters list [filters]                print dead letters as JSON
+  deadletters redrive [filters]             decode dead letters again and store them
+  migrate status                            list migrations and when they were applied
+  migrate up                                apply pending migrations
+  migrate down [N]                          revert the last N migrations (default 1)
+  migrate to VERSION                        migrate up or down to VERSION
+  bench [-rows N] [-batch N] [-modes row,values,copy]
+                                            compare insert modes on a scratch table
+  export -symbols A,B [export flags]        stream stored ticks or bars to a file
+
+Dead-letter filters: -id 1,2 -feed NAME -stage decode|validate|store
+  -symbol SYM -since RFC3339 -until RFC3339 -limit N -all
+
+Export flags: -kind ticks|bars -interval 1m -from TIME -to TIME
+  -format csv|ndjson|parquet -compress none|gzip|zstd -profile CLIENT_ID -o FILE
+  (TIME is RFC3339 or Unix milliseconds)
+`
+
+// runCommand executes a CLI subcommand and returns the process exit code.
+func runCommand(cfg config.Config, args []string) int {
+	switch args[0] {
+	case "instruments":
+		return instrumentsCommand(cfg, args[1:])
+	case "deadletters":
+		return deadLettersCommand(cfg, args[1:])
+	case "migrate":
+		return migrateCommand(cfg, args[1:])
+	case "bench":
+		return benchCommand(cfg, args[1:])
+	case "export":
+		return exportCommand(cfg, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	return 2
+}
+
+// openStore connects to Postgres with the store settings from cfg and
+// migrates the schema, or only checks it without DB_MIGRATE_ON_START.
+func openStore(cfg config.Config) (*storage.Store, error) {
+	store, err := connectStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MigrateOnStart {
+		err = store.EnsureSchema(context.Background())
+	} else {
+		err = store.CheckSchema(context.Background())
+	}
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func connectStore(cfg config.Config) (*storage.Store, error) {
+	store, err := storage.Connect(cfg.DatabaseURL, cfg.UniqueTicks, storage.PartitionOptions{
+		Period:     storage.PartitionPeriod(cfg.PartitionPeriod),
+		Premake:    cfg.PartitionPremake,
+		Retention:  cfg.PartitionRetention,
+		DetachOnly: cfg.PartitionDetachOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	store.SetInsertMode(storage.InsertMode(cfg.StoreInsertMode))
+	return store, nil
+}
+
+func instrumentsCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	ctx := context.Background()
+
+	store, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "import":
+		if len(args) != 2 {
+			fmt.Fprint(os.Stderr, usage)
+			return 2
+		}
+		list, err := instruments.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read %s: %v\n", args[1], err)
+			return 1
+		}
+		if err := store.UpsertInstruments(ctx, list); err != nil {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+		fmt.Printf("imported %d instruments\n", len(list))
+		return 0
+
+	case "list":
+		catalog := instruments.NewCatalog(store)
+		if err := catalog.Reload(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "load: %v\n", err)
+			return 1
+		}
+		list := catalog.All()
+		if len(args) > 1 {
+			list = catalog.ByExchange(args[1])
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(list)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown instruments command %q\n\n%s", args[0], usage)
+	return 2
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/backpressure"
+	"ws_ingestor/internal/app/services/deadletter"
+	"ws_ingestor/internal/app/services/instruments"
+	"ws_ingestor/internal/app/services/storage"
+
+	ws "ws_ingestor/internal/app/services/websocket"
+)
+
+// deadLetterSinks opens the sinks selected by DEADLETTER_SINK. The source
+// used for listing and re-drive is Postgres when enabled, else the file.
+func deadLetterSinks(cfg config.Config, store *storage.Store) ([]deadletter.Sink, deadletter.Source, error) {
+	var (
+		sinks []deadletter.Sink
+		src   deadletter.Source
+	)
+	if cfg.DeadLetterSink == "file" || cfg.DeadLetterSink == "both" {
+		file, err := deadletter.OpenFile(cfg.DeadLetterFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, file)
+		src = file
+	}
+	if cfg.DeadLetterSink == "postgres" || cfg.DeadLetterSink == "both" {
+		sinks = append(sinks, store)
+		src = store
+	}
+	return sinks, src, nil
+}
+
+func deadLettersCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "redrive") {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("deadletters "+args[0], flag.ContinueOnError)
+	q := url.Values{}
+	for _, name := range []string{"id", "feed", "stage", "symbol", "since", "until", "limit"} {
+		fs.Func(name, "filter by "+name, func(v string) error { q.Set(name, v); return nil })
+	}
+	all := fs.Bool("all", false, "include letters already re-driven")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *all {
+		q.Set("all", "true")
+	}
+	filter, err := deadletter.ParseFilter(q)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	store, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+	_, src, err := deadLetterSinks(cfg, store)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if src == nil {
+		fmt.Fprintln(os.Stderr, "dead letters are disabled (DEADLETTER_SINK=none)")
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	if args[0] == "list" {
+		letters, err := src.ListDeadLetters(ctx, filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "list: %v\n", err)
+			return 1
+		}
+		enc.Encode(letters)
+		return 0
+	}
+
+	// Re-drive straight into the store using the feeds' own decoders
+	catalog := instruments.NewCatalog(store)
+	catalog.SetExpiryRules(cfg.ExpiryRules())
+	if err := catalog.Reload(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "load instruments: %v\n", err)
+		return 1
+	}
+	queue, err := backpressure.New(backpressure.Block, 1, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	feeds, err := ws.NewRegistry(cfg.Feeds, queue, catalog, nil, nil, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	res, err := deadletter.Redrive(ctx, src, filter, feeds.Decode, func(ctx context.Context, batch []models.MarketData) error {
+		rejected, err := store.InsertBatch(ctx, batch)
+		if err == nil && len(rejected) > 0 {
+			err = rejected[0].Err
+		}
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "redrive: %v\n", err)
+		return 1
+	}
+	enc.Encode(res)
+	if res.Failed > 0 {
+		return 1
+	}
+	return 0
+}
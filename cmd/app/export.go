@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/services/export"
+	"ws_ingestor/internal/app/services/instruments"
+)
+
+func exportCommand(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	q := url.Values{}
+	for _, name := range []string{"kind", "symbols", "interval", "from", "to", "format", "compress"} {
+		fs.Func(name, "export "+name, func(v string) error { q.Set(name, v); return nil })
+	}
+	profile := fs.String("profile", "", "client ID whose transform profile is applied")
+	output := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	req, err := export.ParseRequest(q)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	store, err := openStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	var clientConfig *dto.ClientConfig
+	if *profile != "" {
+		if clientConfig, err = store.GetClientConfig(ctx, *profile); err != nil {
+			fmt.Fprintf(os.Stderr, "load profile: %v\n", err)
+			return 1
+		}
+		if clientConfig == nil {
+			fmt.Fprintf(os.Stderr, "no client config for %q\n", *profile)
+			return 1
+		}
+	}
+	catalog := instruments.NewCatalog(store)
+	if err := catalog.Reload(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "load instruments: %v\n", err)
+		return 1
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	n, err := export.New(store, catalog).Run(ctx, req, clientConfig, out)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "exported %d %s\n", n, req.Kind)
+	return 0
+}
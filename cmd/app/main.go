@@ -11,7 +11,17 @@ import (
 	"ws_ingestor/internal/app/common/logger"
 	"ws_ingestor/internal/app/config"
 	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/backpressure"
+	"ws_ingestor/internal/app/services/bars"
+	"ws_ingestor/internal/app/services/calendar"
+	"ws_ingestor/internal/app/services/deadletter"
+	"ws_ingestor/internal/app/services/dedup"
+	"ws_ingestor/internal/app/services/instruments"
+	"ws_ingestor/internal/app/services/options"
+	"ws_ingestor/internal/app/services/replay"
+	"ws_ingestor/internal/app/services/rollover"
 	"ws_ingestor/internal/app/services/storage"
+	"ws_ingestor/internal/app/services/wal"
 
 	ws "ws_ingestor/internal/app/services/websocket"
 
@@ -38,17 +48,17 @@ func main() {
 		logger.WithError(err).Fatal("Failed to load configuration")
 	}
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1:]))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
-	// Health and metrics endpoints
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	// Metrics endpoint
 	http.Handle("/metrics", promhttp.Handler())
 
 	go func() {
@@ -58,13 +68,23 @@ func main() {
 		}
 	}()
 
-	dataChan := make(chan models.MarketData, 10000) // Increased buffer for backpressure
+	policy, err := backpressure.ParsePolicy(cfg.BackpressurePolicy)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid backpressure policy")
+	}
+	queue, err := backpressure.New(policy, cfg.IngestBuffer, cfg.SpillDir)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize ingest queue")
+	}
+	go queue.Run(ctx)
+	dataChan := queue.C()
 
-	store, err := storage.NewPostgres(cfg.DatabaseURL)
+	store, err := openStore(cfg)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize database")
 	}
 	defer store.Close()
+	go store.RunPartitionMaintenance(ctx, cfg.PartitionInterval)
 
 	cache, err := storage.NewRedis(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
 	if err != nil {
@@ -72,16 +92,188 @@ func main() {
 	}
 	defer cache.Close()
 
-	proc := processor.New(store, cache, dataChan, cfg.BatchSize, cfg.NumWorkers, cfg.RedisTTL, cfg.FlushInterval)
-	go proc.Start(ctx)
+	catalog := instruments.NewCatalog(store)
+	catalog.SetExpiryRules(cfg.ExpiryRules())
+	if err := catalog.Bootstrap(ctx); err != nil {
+		logger.WithError(err).Fatal("Failed to load instrument master")
+	}
+	if cfg.InstrumentSpecsFile != "" {
+		if err := catalog.LoadSpecs(cfg.InstrumentSpecsFile); err != nil {
+			logger.WithError(err).Fatal("Failed to load instrument specs")
+		}
+	}
+	go catalog.Run(ctx, cfg.InstrumentsReload)
+
+	// Trading hours per exchange drive the stale watchdog and bar sessions
+	cal, err := calendar.Load(cfg.CalendarFile)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load trading calendar")
+	}
+
+	// Frames that fail to decode or validate and batches that cannot be
+	// stored are kept as dead letters
+	deadSinks, deadSource, err := deadLetterSinks(cfg, store)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to open dead-letter sink")
+	}
+	// The dead-letter writer and the bar aggregator run on their own
+	// contexts, stopped at shutdown only once the stages feeding them return
+	deadCtx, stopDead := context.WithCancel(context.Background())
+	defer stopDead()
+	var dead *deadletter.Writer
+	if len(deadSinks) > 0 {
+		dead = deadletter.NewWriter(1000, deadSinks...)
+		go dead.Run(deadCtx)
+	}
+
+	feeds, err := ws.NewRegistry(cfg.Feeds, queue, catalog, cal, store, dead)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize feeds")
+	}
+
+	// Log every tick to disk straight off the ingest queue; dedup, rollover
+	// and the processor run on the replayed stream, the processor
+	// acknowledges persisted offsets and unacknowledged ticks are replayed
+	// on restart
+	procChan := dataChan
+	var (
+		acker   processor.Acker
+		walLog  *wal.Log
+		walDone chan struct{}
+	)
+	if cfg.WALEnabled {
+		log, err := wal.Open(wal.Options{
+			Dir:          cfg.WALDir,
+			SegmentBytes: cfg.WALSegmentBytes,
+			Sync:         wal.SyncPolicy(cfg.WALFsync),
+			SyncInterval: cfg.WALFsyncInterval,
+			Retention:    cfg.WALRetention,
+		})
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to open write-ahead log")
+		}
+		logged := make(chan models.MarketData, cfg.BatchSize*cfg.NumWorkers)
+		walDone = make(chan struct{})
+		go func() {
+			defer close(walDone)
+			log.Run(ctx, procChan, logged)
+		}()
+		procChan, acker, walLog = logged, log, log
+	}
+
+	// Drop ticks repeated across reconnects and redundant feeds before processing
+	if cfg.DedupWindow > 0 {
+		deduped := make(chan models.MarketData, cap(dataChan))
+		go dedup.New(cfg.DedupWindow, acker).Run(ctx, procChan, deduped)
+		procChan = deduped
+	}
+
+	// Roll month-coded futures ahead of expiry and publish continuous aliases
+	var roller *rollover.Engine
+	if cfg.RolloverEnabled {
+		roller = rollover.New(rollover.Options{
+			Rule:             cfg.RolloverRule,
+			PreSubscribeDays: cfg.RolloverPreDays,
+			RollDays:         cfg.RolloverRollDays,
+			AliasSuffix:      cfg.RolloverAliasSuffix,
+		}, catalog, feeds, store)
+		rolled := make(chan models.MarketData, cap(dataChan))
+		go roller.Run(ctx, procChan, rolled)
+		go roller.Start(ctx)
+		procChan = rolled
+	}
+
+	if procChan != dataChan {
+		go backpressure.Gauge(ctx, "process", procChan)
+	}
+
+	// Build OHLCV bars from every processed tick
+	barsCtx, stopBars := context.WithCancel(context.Background())
+	defer stopBars()
+	var (
+		observer processor.Observer
+		barsDone chan struct{}
+	)
+	if cfg.BarsEnabled {
+		intervals, err := bars.ParseIntervals(cfg.BarIntervals)
+		if err != nil {
+			logger.WithError(err).Fatal("Invalid BAR_INTERVALS")
+		}
+		agg := bars.New(intervals, cal, cfg.BarLateness, store, cache, cfg.RedisTTL)
+		barsDone = make(chan struct{})
+		go func() {
+			defer close(barsDone)
+			agg.Run(barsCtx)
+		}()
+		observer = agg
+	}
+
+	proc := processor.New(store, cache, procChan, cfg.BatchSize, cfg.NumWorkers, cfg.RedisTTL, cfg.FlushInterval, acker, dead, observer)
+	procDone := make(chan struct{})
+	go func() {
+		defer close(procDone)
+		proc.Start(ctx)
+	}()
+	go feeds.Start(ctx)
+
+	// Health and admin endpoints, served with the metrics on :9090 only; the
+	// client server on WS_SERVER_ADDR has its own mux
+	http.HandleFunc("/health", ws.NewHealthHandler(feeds))
+	http.HandleFunc("/subscriptions", ws.NewSubscriptionHandler(feeds))
+	http.HandleFunc("/instruments", instruments.NewListHandler(catalog))
+	http.HandleFunc("/instruments/reload", instruments.NewReloadHandler(catalog))
+	http.HandleFunc("/calendar", calendar.NewHandler(cal))
+	if roller != nil {
+		http.HandleFunc("/rollover", rollover.NewAliasHandler(roller))
+	}
+	if deadSource != nil {
+		http.HandleFunc("/deadletters", deadletter.NewListHandler(deadSource))
+		http.HandleFunc("/deadletters/redrive", deadletter.NewRedriveHandler(deadSource, feeds.Decode, func(ctx context.Context, batch []models.MarketData) error {
+			for _, m := range batch {
+				queue.Put(ctx, m)
+			}
+			return nil
+		}))
+	}
+
+	// Option chains subscribe their strikes on OPTIONS_FEED, by default the first feed
+	optionsFeed := cfg.OptionsFeed
+	if optionsFeed == "" {
+		optionsFeed = feeds.Names()[0]
+	}
+	chains := options.NewService(catalog, cache, feeds, optionsFeed, cfg.RolloverAliasSuffix)
+	go chains.Run(ctx, cfg.OptionsRefresh)
+	http.HandleFunc("/chain", options.NewChainHandler(chains))
 
-	client := ws.New(cfg.WebSocketURL, cfg.APIKey, dataChan, cfg.SubscriptionSymbols)
-	go client.Start(ctx)
+	// Replay sessions play history to /ws?replay=<id> clients only
+	replays := replay.NewManager(store, cfg.ReplayDir, cfg.ReplayMaxSessions, cfg.ReplayJoinTimeout)
+	go replays.Run(ctx)
+	http.HandleFunc("/replay", replay.NewHandler(replays))
 
-	server := ws.NewServer(cfg.WSServerAddr, cache, store)
+	server := ws.NewServer(cfg.WSServerAddr, cache, store, catalog, chains, replays)
 	go server.Start(ctx)
 
 	<-sig
 	logger.Info("Shutting down...")
+
+	// Stop in pipeline order: the processor flushes its last batches, the
+	// WAL checkpoints what they acknowledged, the aggregator persists the
+	// bars they completed, the dead-letter writer drains what both
+	// rejected, and the deferred closes of the cache and store run last
 	cancel()
+	<-procDone
+	if walLog != nil {
+		<-walDone
+		if err := walLog.Close(); err != nil {
+			logger.WithError(err).Error("Failed to close write-ahead log")
+		}
+	}
+	stopBars()
+	if barsDone != nil {
+		<-barsDone
+	}
+	stopDead()
+	if dead != nil {
+		<-dead.Done()
+	}
 }
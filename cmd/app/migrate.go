@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/services/storage"
+)
+
+func migrateCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	ctx := context.Background()
+
+	store, err := connectStore(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	status, err := store.MigrationStatus(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "status: %v\n", err)
+		return 1
+	}
+
+	var done []storage.Migration
+	switch args[0] {
+	case "status":
+		for _, st := range status {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-40s %s\n", st.Migration, applied)
+		}
+		return 0
+
+	case "up":
+		// Includes partitioning and the unique-ticks index, like a start would
+		pending := 0
+		for _, st := range status {
+			if st.AppliedAt == nil {
+				pending++
+			}
+		}
+		if err := store.EnsureSchema(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			return 1
+		}
+		fmt.Printf("applied %d migrations, schema at version %d\n", pending, storage.LatestMigration())
+		return 0
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintf(os.Stderr, "invalid step count %q\n", args[1])
+				return 2
+			}
+		}
+		var applied []int64
+		for _, st := range status {
+			if st.AppliedAt != nil {
+				applied = append(applied, st.Version)
+			}
+		}
+		target := int64(0)
+		if steps < len(applied) {
+			target = applied[len(applied)-steps-1]
+		}
+		done, err = store.MigrateTo(ctx, target)
+
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprint(os.Stderr, usage)
+			return 2
+		}
+		target, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || target < 0 || target > storage.LatestMigration() {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return 2
+		}
+		done, err = store.MigrateTo(ctx, target)
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	for _, m := range done {
+		fmt.Println(m)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	return 0
+}
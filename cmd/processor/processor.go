@@ -2,18 +2,34 @@ package processor
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"sync"
 	"time"
 
 	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/common/retry"
 	"ws_ingestor/internal/app/metrics"
 	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/deadletter"
 	"ws_ingestor/internal/app/services/storage"
 
 	"github.com/sirupsen/logrus"
 )
 
+// Acker is told which logged ticks are persisted, e.g. wal.Log.
+type Acker interface {
+	Ack(offsets ...int64)
+}
+
+// Observer sees the rows of every flushed batch that were stored, e.g.
+// bars.Aggregator.
+type Observer interface {
+	Observe(batch []models.MarketData)
+}
+
 type Processor struct {
 	store         *storage.Store
 	cache         *storage.CacheService
@@ -22,10 +38,19 @@ type Processor struct {
 	numWorkers    int
 	ttl           time.Duration
 	flushInterval time.Duration
+	acker         Acker
+	dead          *deadletter.Writer
+	observer      Observer
 	logger        *logrus.Logger
 }
 
-func New(store *storage.Store, cache *storage.CacheService, in <-chan models.MarketData, batchSize int, numWorkers int, ttl time.Duration, flushInterval time.Duration) *Processor {
+// New builds the processor. acker is optional; when set, ticks come from a
+// write-ahead log, store inserts are retried until they succeed and every
+// persisted batch is acknowledged. Otherwise batches that exhaust their
+// retries go to dead (optional). Rows the store rejects always go to dead.
+// observer (optional) is handed the stored rows of every batch once the
+// insert succeeds.
+func New(store *storage.Store, cache *storage.CacheService, in <-chan models.MarketData, batchSize int, numWorkers int, ttl time.Duration, flushInterval time.Duration, acker Acker, dead *deadletter.Writer, observer Observer) *Processor {
 	return &Processor{
 		store:         store,
 		cache:         cache,
@@ -34,23 +59,52 @@ func New(store *storage.Store, cache *storage.CacheService, in <-chan models.Mar
 		numWorkers:    numWorkers,
 		ttl:           ttl,
 		flushInterval: flushInterval,
+		acker:         acker,
+		dead:          dead,
+		observer:      observer,
 		logger:        logger.GetLogger(),
 	}
 }
 
+// Start runs numWorkers workers. Ticks are sharded by symbol hash so each
+// symbol is batched and written by exactly one worker, in arrival order.
 func (p *Processor) Start(ctx context.Context) {
+	shards := make([]chan models.MarketData, p.numWorkers)
 	wg := &sync.WaitGroup{}
-	for i := 0; i < p.numWorkers; i++ {
+	for i := range shards {
+		shards[i] = make(chan models.MarketData, p.batchSize)
 		wg.Add(1)
-		go func() {
+		go func(in <-chan models.MarketData) {
 			defer wg.Done()
-			p.worker(ctx)
-		}()
+			p.worker(ctx, in)
+		}(shards[i])
 	}
+	p.dispatch(ctx, shards)
 	wg.Wait()
 }
 
-func (p *Processor) worker(ctx context.Context) {
+func (p *Processor) dispatch(ctx context.Context, shards []chan models.MarketData) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-p.in:
+			select {
+			case shards[shardOf(d.Name, len(shards))] <- d:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func shardOf(symbol string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return int(h.Sum32() % uint32(n))
+}
+
+func (p *Processor) worker(ctx context.Context, in <-chan models.MarketData) {
 	defer func() {
 		if r := recover(); r != nil {
 			p.logger.WithField("panic", r).Error("Worker panicked")
@@ -63,11 +117,14 @@ func (p *Processor) worker(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
+			// ctx is already cancelled, so the last batch gets its own deadline
 			if len(batch) > 0 {
-				p.flush(ctx, batch)
+				flushCtx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+				p.flush(flushCtx, batch)
+				cancel()
 			}
 			return
-		case d := <-p.in:
+		case d := <-in:
 			batch = append(batch, d)
 			if len(batch) >= p.batchSize {
 				p.flush(ctx, batch)
@@ -82,38 +139,126 @@ func (p *Processor) worker(ctx context.Context) {
 	}
 }
 
+// shutdownFlushTimeout bounds the flush of each worker's last batch.
+const shutdownFlushTimeout = 10 * time.Second
+
+func newFlushBackoff() *retry.Backoff {
+	return retry.NewBackoff(time.Second, 4*time.Second)
+}
+
 func (p *Processor) flush(ctx context.Context, batch []models.MarketData) {
 	start := time.Now()
 	const maxRetries = 3
-	var err error
 
-	// Retry store insert
-	for i := 0; i < maxRetries; i++ {
-		if err = p.store.InsertBatch(ctx, batch); err == nil {
-			break
-		}
-		p.logger.Warn(fmt.Sprintf("Store insert failed (attempt %d/%d): %v", i+1, maxRetries, err))
-		metrics.ErrorsTotal.WithLabelValues("store_insert").Inc()
-		time.Sleep(time.Duration(i+1) * time.Second) // Exponential backoff
+	// With a WAL behind us nothing is lost by waiting, so keep retrying
+	storeRetries, backoff := maxRetries, newFlushBackoff()
+	if p.acker != nil {
+		storeRetries, backoff = 0, retry.NewBackoff(time.Second, 30*time.Second)
 	}
+
+	// Retry store insert; rows rejected for their own data are not retried
+	var rejected []storage.Rejected
+	err := retry.Do(ctx, storeRetries, backoff, func() error {
+		var err error
+		rejected, err = p.store.InsertBatch(ctx, batch)
+		if err != nil {
+			metrics.ErrorsTotal.WithLabelValues("store_insert").Inc()
+		}
+		return err
+	}, func(attempt int, err error) {
+		p.logger.Warn(fmt.Sprintf("Store insert failed (attempt %d): %v", attempt, err))
+	})
 	if err != nil {
 		p.logger.Error(fmt.Sprintf("Store insert failed after retries: %v", err))
+		// A cancelled insert says nothing about the rows, so they are not
+		// dead-lettered
+		if p.acker == nil && !errors.Is(err, context.Canceled) {
+			p.deadLetter(batch, err)
+		}
+	} else {
+		// Rejected rows are dead-lettered even with a WAL: retrying cannot help
+		if len(rejected) > 0 {
+			p.logger.Warn(fmt.Sprintf("Store rejected %d of %d ticks", len(rejected), len(batch)))
+		}
+		for _, r := range rejected {
+			p.deadLetter([]models.MarketData{r.Record}, r.Err)
+		}
+		if p.acker != nil {
+			offsets := make([]int64, 0, len(batch))
+			for _, d := range batch {
+				if d.Offset > 0 {
+					offsets = append(offsets, d.Offset)
+				}
+			}
+			p.acker.Ack(offsets...)
+		}
 	}
 
+	storeErr := err
+
 	// Retry cache insert
-	for i := 0; i < maxRetries; i++ {
-		if err = p.cache.InsertBatch(ctx, batch, p.ttl); err == nil {
-			break
+	err = retry.Do(ctx, maxRetries, newFlushBackoff(), func() error {
+		err := p.cache.InsertBatch(ctx, batch, p.ttl)
+		if err != nil {
+			metrics.ErrorsTotal.WithLabelValues("cache_insert").Inc()
 		}
-		p.logger.Warn(fmt.Sprintf("Cache insert failed (attempt %d/%d): %v", i+1, maxRetries, err))
-		metrics.ErrorsTotal.WithLabelValues("cache_insert").Inc()
-		time.Sleep(time.Duration(i+1) * time.Second)
-	}
+		return err
+	}, func(attempt int, err error) {
+		p.logger.Warn(fmt.Sprintf("Cache insert failed (attempt %d/%d): %v", attempt, maxRetries, err))
+	})
 	if err != nil {
 		p.logger.Error(fmt.Sprintf("Cache insert failed after retries: %v", err))
 	}
 
+	// Only rows that reached the store count towards bars, so a batch
+	// replayed from the WAL after a failed insert is not observed twice
+	if p.observer != nil && storeErr == nil {
+		p.observer.Observe(stored(batch, rejected))
+	}
+
 	metrics.BatchInserts.Inc()
-	metrics.MessagesProcessed.Add(float64(len(batch)))
+	perFeed := make(map[string]int)
+	for _, d := range batch {
+		perFeed[d.Feed]++
+	}
+	for feed, n := range perFeed {
+		metrics.MessagesProcessed.WithLabelValues(feed).Add(float64(n))
+	}
 	metrics.ProcessingLatency.Observe(time.Since(start).Seconds())
 }
+
+// stored returns the ticks of batch the store persisted: neither rejected
+// nor skipped by InsertBatch as an alias copy or for lacking a timestamp.
+func stored(batch []models.MarketData, rejected []storage.Rejected) []models.MarketData {
+	skip := make(map[int]struct{}, len(rejected))
+	for _, r := range rejected {
+		skip[r.Index] = struct{}{}
+	}
+	out := make([]models.MarketData, 0, len(batch)-len(skip))
+	for i, d := range batch {
+		if _, ok := skip[i]; ok || d.Alias || d.Timestamp == 0 {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// deadLetter records every tick of a batch that could not be stored.
+func (p *Processor) deadLetter(batch []models.MarketData, err error) {
+	now := time.Now()
+	for _, d := range batch {
+		raw, merr := json.Marshal(d)
+		if merr != nil {
+			continue
+		}
+		p.dead.Put(models.DeadLetter{
+			Feed:       d.Feed,
+			Symbol:     d.Name,
+			Stage:      models.StageStore,
+			Reason:     err.Error(),
+			Raw:        raw,
+			ReceivedAt: now,
+		})
+	}
+}
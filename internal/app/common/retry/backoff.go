@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff produces exponentially growing delays with random jitter.
+// It is not safe for concurrent use.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomised, 0..1.
+	Jitter float64
+	// MinStable is how long a connection must stay up before ResetIfStable
+	// resets the backoff; shorter sessions keep escalating the delay.
+	MinStable time.Duration
+
+	attempt int
+}
+
+func NewBackoff(initial, max time.Duration) *Backoff {
+	return &Backoff{Initial: initial, Max: max, Multiplier: 2, Jitter: 0.2}
+}
+
+// Next returns the delay before the next attempt and advances the sequence.
+func (b *Backoff) Next() time.Duration {
+	mult := b.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	d := float64(b.Initial) * math.Pow(mult, float64(b.attempt))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	} else {
+		b.attempt++
+	}
+
+	if b.Jitter > 0 {
+		j := math.Min(b.Jitter, 1)
+		d = d*(1-j) + rand.Float64()*d*j*2
+		if b.Max > 0 && d > float64(b.Max) {
+			d = float64(b.Max)
+		}
+	}
+	return time.Duration(d)
+}
+
+// Attempts returns how many delays have been handed out since the last reset.
+func (b *Backoff) Attempts() int {
+	return b.attempt
+}
+
+// Saturate advances the sequence to Max, so every later delay is Max less
+// jitter until the next Reset.
+func (b *Backoff) Saturate() {
+	if b.Initial <= 0 || b.Max <= 0 {
+		return
+	}
+	mult := b.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	for float64(b.Initial)*math.Pow(mult, float64(b.attempt)) < float64(b.Max) {
+		b.attempt++
+	}
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// ResetIfStable resets the backoff when uptime reached MinStable and reports
+// whether it did.
+func (b *Backoff) ResetIfStable(uptime time.Duration) bool {
+	if uptime >= b.MinStable {
+		b.Reset()
+		return true
+	}
+	return false
+}
+
+// Sleep waits for d or until ctx is done, returning ctx.Err() in the latter case.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"context"
+	"errors"
+)
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string { return p.err.Error() }
+func (p *permanentError) Unwrap() error { return p.err }
+
+// Permanent marks err as not worth retrying; Do returns it immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn up to attempts times, sleeping b.Next() between failures; with
+// attempts <= 0 it retries until fn succeeds or ctx is done. onRetry, if set,
+// is called before each sleep with the 1-based attempt that failed. The last
+// error is returned, unwrapped from Permanent.
+func Do(ctx context.Context, attempts int, b *Backoff, fn func() error, onRetry func(attempt int, err error)) error {
+	var err error
+	for i := 1; attempts <= 0 || i <= attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if i == attempts {
+			break
+		}
+		if onRetry != nil {
+			onRetry(i, err)
+		}
+		if serr := Sleep(ctx, b.Next()); serr != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	common "ws_ingestor/internal/app/common/exception_handler"
@@ -22,6 +24,55 @@ type Config struct {
 	RedisTTL            time.Duration `mapstructure:"REDIS_TTL"`
 	FlushInterval       time.Duration `mapstructure:"FLUSH_INTERVAL"`
 	SubscriptionSymbols []string      `mapstructure:"SUBSCRIPTION_SYMBOLS"`
+	FeedsFile           string        `mapstructure:"FEEDS_FILE"`
+	PingInterval        time.Duration `mapstructure:"WS_PING_INTERVAL"`
+	ReadTimeout         time.Duration `mapstructure:"WS_READ_TIMEOUT"`
+	StaleAfter          time.Duration `mapstructure:"WS_STALE_AFTER"`
+	SymbolStaleAfter    time.Duration `mapstructure:"WS_SYMBOL_STALE_AFTER"`
+	BackoffInitial      time.Duration `mapstructure:"WS_BACKOFF_INITIAL"`
+	BackoffMax          time.Duration `mapstructure:"WS_BACKOFF_MAX"`
+	BackoffJitter       float64       `mapstructure:"WS_BACKOFF_JITTER"`
+	MinStable           time.Duration `mapstructure:"WS_MIN_STABLE"`
+	FailureThreshold    int           `mapstructure:"WS_FAILURE_THRESHOLD"`
+	GapThreshold        time.Duration `mapstructure:"WS_GAP_THRESHOLD"`
+	DedupWindow         int           `mapstructure:"DEDUP_WINDOW"`
+	UniqueTicks         bool          `mapstructure:"STORE_UNIQUE_TICKS"`
+	StoreInsertMode     string        `mapstructure:"STORE_INSERT_MODE"`
+	MigrateOnStart      bool          `mapstructure:"DB_MIGRATE_ON_START"`
+	PartitionPeriod     string        `mapstructure:"MARKET_DATA_PARTITION"`
+	PartitionPremake    int           `mapstructure:"MARKET_DATA_PREMAKE"`
+	PartitionRetention  time.Duration `mapstructure:"MARKET_DATA_RETENTION"`
+	PartitionDetachOnly bool          `mapstructure:"MARKET_DATA_RETENTION_DETACH"`
+	PartitionInterval   time.Duration `mapstructure:"PARTITION_MAINTENANCE_INTERVAL"`
+	InstrumentSpecsFile string        `mapstructure:"INSTRUMENT_SPECS_FILE"`
+	InstrumentsReload   time.Duration `mapstructure:"INSTRUMENTS_RELOAD_INTERVAL"`
+	RolloverEnabled     bool          `mapstructure:"ROLLOVER_ENABLED"`
+	RolloverRule        string        `mapstructure:"ROLLOVER_RULE"`
+	RolloverPreDays     int           `mapstructure:"ROLLOVER_PRESUBSCRIBE_DAYS"`
+	RolloverRollDays    int           `mapstructure:"ROLLOVER_ROLL_DAYS"`
+	RolloverAliasSuffix string        `mapstructure:"ROLLOVER_ALIAS_SUFFIX"`
+	OptionsFeed         string        `mapstructure:"OPTIONS_FEED"`
+	OptionsRefresh      time.Duration `mapstructure:"OPTIONS_REFRESH_INTERVAL"`
+	ExpiryRulesSpec     string        `mapstructure:"EXPIRY_RULES"`
+	BackpressurePolicy  string        `mapstructure:"BACKPRESSURE_POLICY"`
+	IngestBuffer        int           `mapstructure:"INGEST_BUFFER"`
+	SpillDir            string        `mapstructure:"SPILL_DIR"`
+	WALEnabled          bool          `mapstructure:"WAL_ENABLED"`
+	WALDir              string        `mapstructure:"WAL_DIR"`
+	WALSegmentBytes     int64         `mapstructure:"WAL_SEGMENT_BYTES"`
+	WALFsync            string        `mapstructure:"WAL_FSYNC"`
+	WALFsyncInterval    time.Duration `mapstructure:"WAL_FSYNC_INTERVAL"`
+	WALRetention        time.Duration `mapstructure:"WAL_RETENTION"`
+	DeadLetterSink      string        `mapstructure:"DEADLETTER_SINK"`
+	BarsEnabled         bool          `mapstructure:"BARS_ENABLED"`
+	BarIntervals        string        `mapstructure:"BAR_INTERVALS"`
+	BarLateness         time.Duration `mapstructure:"BAR_LATENESS"`
+	CalendarFile        string        `mapstructure:"CALENDAR_FILE"`
+	DeadLetterFile      string        `mapstructure:"DEADLETTER_FILE"`
+	ReplayDir           string        `mapstructure:"REPLAY_DIR"`
+	ReplayMaxSessions   int           `mapstructure:"REPLAY_MAX_SESSIONS"`
+	ReplayJoinTimeout   time.Duration `mapstructure:"REPLAY_JOIN_TIMEOUT"`
+	Feeds               []FeedConfig  `mapstructure:"-"`
 }
 
 func Load() (Config, error) {
@@ -37,6 +88,51 @@ func Load() (Config, error) {
 	viper.SetDefault("REDIS_TTL", "24h")
 	viper.SetDefault("FLUSH_INTERVAL", "2s")
 	viper.SetDefault("SUBSCRIPTION_SYMBOLS", []string{"USDSGD"})
+	viper.SetDefault("WS_PING_INTERVAL", "15s")
+	viper.SetDefault("WS_READ_TIMEOUT", "45s")
+	viper.SetDefault("WS_STALE_AFTER", "0s")
+	viper.SetDefault("WS_SYMBOL_STALE_AFTER", "0s")
+	viper.SetDefault("WS_BACKOFF_INITIAL", "1s")
+	viper.SetDefault("WS_BACKOFF_MAX", "30s")
+	viper.SetDefault("WS_BACKOFF_JITTER", 0.2)
+	viper.SetDefault("WS_MIN_STABLE", "30s")
+	viper.SetDefault("WS_FAILURE_THRESHOLD", 5)
+	viper.SetDefault("WS_GAP_THRESHOLD", "0s")
+	viper.SetDefault("DEDUP_WINDOW", 100000)
+	viper.SetDefault("STORE_UNIQUE_TICKS", false)
+	viper.SetDefault("STORE_INSERT_MODE", "row")
+	viper.SetDefault("DB_MIGRATE_ON_START", true)
+	viper.SetDefault("MARKET_DATA_PARTITION", "none")
+	viper.SetDefault("MARKET_DATA_PREMAKE", 3)
+	viper.SetDefault("MARKET_DATA_RETENTION", "0s")
+	viper.SetDefault("MARKET_DATA_RETENTION_DETACH", false)
+	viper.SetDefault("PARTITION_MAINTENANCE_INTERVAL", "1h")
+	viper.SetDefault("INSTRUMENTS_RELOAD_INTERVAL", "1m")
+	viper.SetDefault("ROLLOVER_ENABLED", false)
+	viper.SetDefault("ROLLOVER_RULE", "date")
+	viper.SetDefault("ROLLOVER_PRESUBSCRIBE_DAYS", 5)
+	viper.SetDefault("ROLLOVER_ROLL_DAYS", 1)
+	viper.SetDefault("ROLLOVER_ALIAS_SUFFIX", "-I")
+	viper.SetDefault("OPTIONS_REFRESH_INTERVAL", "5s")
+	viper.SetDefault("BACKPRESSURE_POLICY", "block")
+	viper.SetDefault("INGEST_BUFFER", 10000)
+	viper.SetDefault("SPILL_DIR", "./spill")
+	viper.SetDefault("WAL_ENABLED", false)
+	viper.SetDefault("BARS_ENABLED", false)
+	viper.SetDefault("BAR_INTERVALS", "1s,1m,5m,15m,1h,1d")
+	viper.SetDefault("BAR_LATENESS", "2s")
+	viper.SetDefault("CALENDAR_FILE", "")
+	viper.SetDefault("WAL_DIR", "./wal")
+	viper.SetDefault("WAL_SEGMENT_BYTES", 64<<20)
+	viper.SetDefault("WAL_FSYNC", "interval")
+	viper.SetDefault("WAL_FSYNC_INTERVAL", "1s")
+	viper.SetDefault("WAL_RETENTION", "0s")
+	viper.SetDefault("DEADLETTER_SINK", "postgres")
+	viper.SetDefault("DEADLETTER_FILE", "./deadletters.ndjson")
+	viper.SetDefault("REPLAY_DIR", "./replay")
+	viper.SetDefault("REPLAY_MAX_SESSIONS", 4)
+	viper.SetDefault("REPLAY_JOIN_TIMEOUT", "5m")
+	viper.SetDefault("EXPIRY_RULES", "nse:last-tuesday,mcx:last-business-day")
 
 	if err := viper.ReadInConfig(); err != nil {
 		// Fallback to env if .env not found
@@ -71,13 +167,75 @@ func Load() (Config, error) {
 	// Subscription symbols
 	symbolsStr := os.Getenv("SUBSCRIPTION_SYMBOLS")
 	if symbolsStr != "" {
-		// Parse comma-separated
-		// For simplicity, assume env is comma-separated
-		cfg.SubscriptionSymbols = []string{"USDSGD"} // Keep default for now
+		cfg.SubscriptionSymbols = splitSymbols(symbolsStr)
 	}
 
-	if cfg.WebSocketURL == "" || cfg.APIKey == "" || cfg.DatabaseURL == "" {
+	// Feeds: either a feeds file or the single legacy WS_URL/WS_API_KEY feed
+	if cfg.FeedsFile != "" {
+		feeds, err := loadFeeds(cfg.FeedsFile)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Feeds = feeds
+	} else {
+		if cfg.WebSocketURL == "" || cfg.APIKey == "" {
+			return cfg, common.NewCustomError(common.ErrConfigLoad, "Missing required environment variables", nil)
+		}
+		cfg.Feeds = []FeedConfig{{
+			Name:    DefaultFeedName,
+			URL:     cfg.WebSocketURL,
+			APIKey:  cfg.APIKey,
+			Symbols: cfg.SubscriptionSymbols,
+		}}
+	}
+	applyFeedDefaults(cfg.Feeds, cfg)
+	if err := validateFeeds(cfg.Feeds); err != nil {
+		return cfg, err
+	}
+
+	if cfg.RolloverRule != "date" && cfg.RolloverRule != "oi" {
+		return cfg, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Invalid ROLLOVER_RULE %q", cfg.RolloverRule), nil)
+	}
+
+	switch cfg.StoreInsertMode {
+	case "row", "values", "copy":
+	default:
+		return cfg, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Invalid STORE_INSERT_MODE %q", cfg.StoreInsertMode), nil)
+	}
+
+	switch cfg.PartitionPeriod {
+	case "none", "day", "week":
+	default:
+		return cfg, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Invalid MARKET_DATA_PARTITION %q", cfg.PartitionPeriod), nil)
+	}
+
+	switch cfg.WALFsync {
+	case "always", "interval", "none":
+	default:
+		return cfg, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Invalid WAL_FSYNC %q", cfg.WALFsync), nil)
+	}
+
+	switch cfg.DeadLetterSink {
+	case "postgres", "file", "both", "none":
+	default:
+		return cfg, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Invalid DEADLETTER_SINK %q", cfg.DeadLetterSink), nil)
+	}
+
+	if cfg.DatabaseURL == "" {
 		return cfg, common.NewCustomError(common.ErrConfigLoad, "Missing required environment variables", nil)
 	}
 	return cfg, nil
 }
+
+// ExpiryRules parses EXPIRY_RULES ("nse:last-tuesday,mcx:...")
+// into exchange -> rule.
+func (c Config) ExpiryRules() map[string]string {
+	rules := make(map[string]string)
+	for _, pair := range splitSymbols(c.ExpiryRulesSpec) {
+		exch, rule, ok := strings.Cut(pair, ":")
+		if ok {
+			rules[strings.TrimSpace(exch)] = strings.TrimSpace(rule)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	common "ws_ingestor/internal/app/common/exception_handler"
+
+	"github.com/spf13/viper"
+)
+
+const DefaultFeedName = "default"
+
+// FeedConfig describes one upstream market data provider.
+type FeedConfig struct {
+	Name       string   `mapstructure:"name"`
+	URL        string   `mapstructure:"url"`
+	AuthHeader string   `mapstructure:"auth_header"`
+	APIKey     string   `mapstructure:"api_key"`
+	Symbols    []string `mapstructure:"symbols"`
+	Parser     string   `mapstructure:"parser"`
+
+	// Mapping configures the "mapping" parser.
+	Mapping *MappingConfig `mapstructure:"mapping"`
+
+	// Heartbeat and staleness; zero values inherit the WS_* defaults.
+	// StaleAfter/SymbolStaleAfter force a reconnect when no tick arrived for
+	// the feed / for any subscribed symbol within the window.
+	PingInterval     time.Duration `mapstructure:"ping_interval"`
+	ReadTimeout      time.Duration `mapstructure:"read_timeout"`
+	StaleAfter       time.Duration `mapstructure:"stale_after"`
+	SymbolStaleAfter time.Duration `mapstructure:"symbol_stale_after"`
+
+	// Gap detection: a symbol whose consecutive ticks are further apart than
+	// GapThreshold (0 disables) or whose sequence number skips is recorded
+	// as a gap. With GapFillURL set the range is backfilled from the vendor's
+	// REST snapshot endpoint, decoded with GapFillParser (default "batch").
+	GapThreshold  time.Duration `mapstructure:"gap_threshold"`
+	GapFillURL    string        `mapstructure:"gap_fill_url"`
+	GapFillParser string        `mapstructure:"gap_fill_parser"`
+
+	// Reconnect backoff; zero values inherit the WS_* defaults.
+	BackoffInitial   time.Duration `mapstructure:"backoff_initial"`
+	BackoffMax       time.Duration `mapstructure:"backoff_max"`
+	BackoffJitter    float64       `mapstructure:"backoff_jitter"`
+	MinStable        time.Duration `mapstructure:"min_stable"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+}
+
+// MappingConfig maps fields of an arbitrary JSON envelope onto MarketData
+// using JSONPath-style paths such as "$.payload.ticks[0].px". Lists are used
+// instead of maps because config keys are case-folded and split on dots.
+type MappingConfig struct {
+	// Root points at one record or an array of records. Defaults to "$".
+	Root string `mapstructure:"root"`
+	// Match lists conditions on the whole frame; frames that do not satisfy
+	// all of them are ignored (e.g. heartbeats or acks).
+	Match []MatchRule `mapstructure:"match"`
+
+	// Paths below are relative to each record.
+	Name          string `mapstructure:"name"`
+	Timestamp     string `mapstructure:"timestamp"`
+	TimestampUnit string `mapstructure:"timestamp_unit"` // s, ms (default), us, ns
+	Exchange      string `mapstructure:"exchange"`
+	Seq           string `mapstructure:"seq"`
+	// Data selects the payload subtree; defaults to the whole record.
+	// Ignored when Fields is set.
+	Data   string         `mapstructure:"data"`
+	Fields []FieldMapping `mapstructure:"fields"`
+	// Aliases rename payload keys to the names typed views are derived
+	// from, e.g. c -> close for a vendor that sends one-letter bar fields.
+	Aliases []KeyAlias `mapstructure:"aliases"`
+}
+
+type MatchRule struct {
+	Path  string `mapstructure:"path"`
+	Value string `mapstructure:"value"`
+}
+
+type FieldMapping struct {
+	Field string `mapstructure:"field"`
+	Path  string `mapstructure:"path"`
+}
+
+type KeyAlias struct {
+	From string `mapstructure:"from"`
+	To   string `mapstructure:"to"`
+}
+
+// loadFeeds reads the feed list from a JSON/YAML/TOML file with a top level
+// "feeds" key. Values such as api_key may reference env vars as ${VAR}.
+func loadFeeds(path string) ([]FeedConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Failed to read feeds file %s", path), err)
+	}
+
+	var feeds []FeedConfig
+	if err := v.UnmarshalKey("feeds", &feeds); err != nil {
+		return nil, common.NewCustomError(common.ErrConfigLoad, "Failed to unmarshal feeds", err)
+	}
+	for i := range feeds {
+		feeds[i].URL = os.ExpandEnv(feeds[i].URL)
+		feeds[i].APIKey = os.ExpandEnv(feeds[i].APIKey)
+		feeds[i].GapFillURL = os.ExpandEnv(feeds[i].GapFillURL)
+	}
+	return feeds, nil
+}
+
+// applyFeedDefaults fills unset per-feed heartbeat, backoff and gap settings from the global config.
+func applyFeedDefaults(feeds []FeedConfig, cfg Config) {
+	for i := range feeds {
+		f := &feeds[i]
+		if f.PingInterval == 0 {
+			f.PingInterval = cfg.PingInterval
+		}
+		if f.ReadTimeout == 0 {
+			f.ReadTimeout = cfg.ReadTimeout
+		}
+		if f.StaleAfter == 0 {
+			f.StaleAfter = cfg.StaleAfter
+		}
+		if f.SymbolStaleAfter == 0 {
+			f.SymbolStaleAfter = cfg.SymbolStaleAfter
+		}
+		if f.BackoffInitial == 0 {
+			f.BackoffInitial = cfg.BackoffInitial
+		}
+		if f.BackoffMax == 0 {
+			f.BackoffMax = cfg.BackoffMax
+		}
+		if f.BackoffJitter == 0 {
+			f.BackoffJitter = cfg.BackoffJitter
+		}
+		if f.MinStable == 0 {
+			f.MinStable = cfg.MinStable
+		}
+		if f.FailureThreshold == 0 {
+			f.FailureThreshold = cfg.FailureThreshold
+		}
+		if f.GapThreshold == 0 {
+			f.GapThreshold = cfg.GapThreshold
+		}
+	}
+}
+
+func validateFeeds(feeds []FeedConfig) error {
+	if len(feeds) == 0 {
+		return common.NewCustomError(common.ErrConfigLoad, "No feeds configured", nil)
+	}
+	seen := make(map[string]struct{}, len(feeds))
+	for i := range feeds {
+		f := &feeds[i]
+		if f.Name == "" || f.URL == "" {
+			return common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Feed #%d requires name and url", i+1), nil)
+		}
+		if _, dup := seen[f.Name]; dup {
+			return common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Duplicate feed name %q", f.Name), nil)
+		}
+		seen[f.Name] = struct{}{}
+		if f.FailureThreshold < 1 {
+			return common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Feed %q needs a failure_threshold (or WS_FAILURE_THRESHOLD) of at least 1, got %d", f.Name, f.FailureThreshold), nil)
+		}
+
+		if f.AuthHeader == "" {
+			f.AuthHeader = "x-api-key"
+		}
+		if f.Parser == "" {
+			f.Parser = "json"
+		}
+		if f.GapFillParser == "" {
+			f.GapFillParser = "batch"
+		}
+	}
+	return nil
+}
+
+func splitSymbols(s string) []string {
+	var out []string
+	for _, sym := range strings.Split(s, ",") {
+		if sym = strings.TrimSpace(sym); sym != "" {
+			out = append(out, sym)
+		}
+	}
+	return out
+}
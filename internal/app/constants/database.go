@@ -4,4 +4,9 @@ const (
 	MARKET_DATA_TABLE_NAME     = "market_data"
 	API_KEYS_TABLE_NAME        = "api_keys"
 	CLIENTS_CONFIGS_TABLE_NAME = "clients_configs"
+	DATA_GAPS_TABLE_NAME       = "data_gaps"
+	INSTRUMENTS_TABLE_NAME     = "instruments"
+	ROLL_EVENTS_TABLE_NAME     = "roll_events"
+	DEAD_LETTERS_TABLE_NAME    = "dead_letters"
+	BARS_TABLE_NAME            = "bars"
 )
@@ -1,5 +1,8 @@
 package constants
 
+// Legacy hard-coded symbol lists. They are only used to seed an empty
+// instrument master; lookups go through instruments.Catalog.
+
 var NSE_SYMBOLS = []string{
 	"360ONE25DECFUT",
 	"ABB25DECFUT",
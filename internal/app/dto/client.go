@@ -1,5 +1,7 @@
 package dto
 
+import "ws_ingestor/internal/app/models"
+
 type ClientConfig struct {
 	Symbols map[string]SymbolConfig `json:"symbols"`
 }
@@ -13,11 +15,11 @@ type SymbolConfig struct {
 }
 
 type ValueRule struct {
-	Op    string  `json:"op"`
-	Value float64 `json:"value"`
+	Op    string         `json:"op"`
+	Value models.Decimal `json:"value"`
 }
 
 type ValueTransform struct {
-	Operation string  `json:"operation"` // "multiply", "add", "subtract", "divide"
-	Value     float64 `json:"value"`
+	Operation string         `json:"operation"` // "multiply", "add", "subtract", "divide"
+	Value     models.Decimal `json:"value"`
 }
@@ -6,15 +6,15 @@ import (
 )
 
 var (
-	MessagesReceived = promauto.NewCounter(prometheus.CounterOpts{
+	MessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "ws_ingestor_messages_received_total",
 		Help: "Total number of messages received from websocket",
-	})
+	}, []string{"feed"})
 
-	MessagesProcessed = promauto.NewCounter(prometheus.CounterOpts{
+	MessagesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "ws_ingestor_messages_processed_total",
 		Help: "Total number of messages processed",
-	})
+	}, []string{"feed"})
 
 	BatchInserts = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "ws_ingestor_batch_inserts_total",
@@ -31,4 +31,104 @@ var (
 		Help:    "Latency of processing batches",
 		Buckets: prometheus.DefBuckets,
 	})
+
+	FeedStalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_feed_stalls_total",
+		Help: "Number of forced reconnects caused by a stalled feed or symbol",
+	}, []string{"feed", "scope"})
+
+	FeedStale = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ws_ingestor_feed_stale",
+		Help: "1 when the feed has not delivered ticks within its stale window",
+	}, []string{"feed"})
+
+	SymbolStale = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ws_ingestor_symbol_stale",
+		Help: "1 when a subscribed symbol has not ticked within its stale window",
+	}, []string{"feed", "symbol"})
+
+	FeedState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ws_ingestor_feed_state",
+		Help: "Connection state of each feed; 1 for the current state",
+	}, []string{"feed", "state"})
+
+	GapsDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_gaps_detected_total",
+		Help: "Number of per-symbol tick gaps detected",
+	}, []string{"feed", "kind"})
+
+	GapRecordsFilled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_gap_records_filled_total",
+		Help: "Number of records backfilled through a gap filler",
+	}, []string{"feed"})
+
+	DuplicatesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_duplicates_dropped_total",
+		Help: "Number of duplicate ticks dropped, by stage (memory window or store constraint)",
+	}, []string{"stage"})
+
+	InstrumentsLoaded = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_ingestor_instruments_loaded",
+		Help: "Number of instruments in the in-memory instrument master",
+	})
+
+	Rollovers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_rollovers_total",
+		Help: "Number of futures contract rollovers, by root symbol",
+	}, []string{"root"})
+
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ws_ingestor_queue_depth",
+		Help: "Number of ticks waiting in each pipeline channel or overflow buffer",
+	}, []string{"queue"})
+
+	TicksDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_ticks_dropped_total",
+		Help: "Number of ticks dropped or conflated by the backpressure policy, per symbol",
+	}, []string{"policy", "symbol"})
+
+	WALPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_ingestor_wal_pending",
+		Help: "Number of write-ahead log records not yet acknowledged by the processor",
+	})
+
+	WALSegments = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_ingestor_wal_segments",
+		Help: "Number of write-ahead log segment files on disk",
+	})
+
+	DeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_dead_letters_total",
+		Help: "Number of messages sent to the dead-letter sink, by feed and stage",
+	}, []string{"feed", "stage"})
+
+	StaleCacheWrites = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ws_ingestor_cache_stale_writes_total",
+		Help: "Number of cache writes skipped because the cached tick was newer",
+	})
+
+	RejectedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_store_rejected_rows_total",
+		Help: "Number of ticks refused by the database, by SQLSTATE error class",
+	}, []string{"class"})
+
+	Partitions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ws_ingestor_market_data_partitions",
+		Help: "Number of range partitions attached to the market data table",
+	})
+
+	BarsClosed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "ws_ingestor_bars_closed_total",
+		Help: "Number of OHLCV bars closed and stored",
+	})
+
+	LateTicks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_bar_late_ticks_total",
+		Help: "Number of ticks that arrived after their bar was closed, by interval",
+	}, []string{"interval"})
+
+	OffSessionTicks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ws_ingestor_bar_off_session_ticks_total",
+		Help: "Number of ticks left out of bars because their exchange was closed, by exchange",
+	}, []string{"exchange"})
 )
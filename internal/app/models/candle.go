@@ -0,0 +1,18 @@
+package models
+
+// Candle is an OHLCV bar aggregated from ticks. Start and End are Unix
+// milliseconds; End is exclusive. Final is set once the bar is closed.
+type Candle struct {
+	Symbol   string   `json:"symbol"`
+	Exchange string   `json:"exchange"`
+	Interval string   `json:"interval"`
+	Start    int64    `json:"start"`
+	End      int64    `json:"end"`
+	Open     Decimal  `json:"open"`
+	High     Decimal  `json:"high"`
+	Low      Decimal  `json:"low"`
+	Close    Decimal  `json:"close"`
+	Volume   *Decimal `json:"volume,omitempty"`
+	Ticks    int      `json:"ticks"`
+	Final    bool     `json:"final"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DataGap is a stretch of missing ticks for one symbol on one feed. From/To
+// are the timestamps of the ticks either side of the gap; FromSeq/ToSeq are
+// set when the feed provides sequence numbers.
+type DataGap struct {
+	ID         int64     `json:"id"`
+	Feed       string    `json:"feed"`
+	Symbol     string    `json:"symbol"`
+	Kind       string    `json:"kind"` // "timestamp" or "sequence"
+	From       int64     `json:"from"`
+	To         int64     `json:"to"`
+	FromSeq    int64     `json:"from_seq,omitempty"`
+	ToSeq      int64     `json:"to_seq,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+}
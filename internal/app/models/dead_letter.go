@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+)
+
+// Dead-letter stages: where in the pipeline a message was rejected.
+const (
+	StageDecode   = "decode"
+	StageValidate = "validate"
+	StageStore    = "store"
+)
+
+// DeadLetter is a message the pipeline could not accept or persist. Raw is
+// the upstream frame for decode/validate rejects and the tick as JSON for
+// store failures.
+type DeadLetter struct {
+	ID         int64      `json:"id,omitempty"`
+	Feed       string     `json:"feed"`
+	Symbol     string     `json:"symbol,omitempty"`
+	Stage      string     `json:"stage"`
+	Reason     string     `json:"reason"`
+	Raw        []byte     `json:"-"`
+	ReceivedAt time.Time  `json:"received_at"`
+	RedrivenAt *time.Time `json:"redriven_at,omitempty"`
+}
+
+// MarshalJSON shows text frames as-is under "raw" and binary frames as
+// base64 under "raw_base64".
+func (d DeadLetter) MarshalJSON() ([]byte, error) {
+	type plain DeadLetter
+	out := struct {
+		plain
+		RawText   *string `json:"raw,omitempty"`
+		RawBase64 []byte  `json:"raw_base64,omitempty"`
+	}{plain: plain(d)}
+	if utf8.Valid(d.Raw) {
+		s := string(d.Raw)
+		out.RawText = &s
+	} else {
+		out.RawBase64 = d.Raw
+	}
+	return json.Marshal(out)
+}
+
+func (d *DeadLetter) UnmarshalJSON(b []byte) error {
+	type plain DeadLetter
+	var in struct {
+		plain
+		RawText   *string `json:"raw"`
+		RawBase64 []byte  `json:"raw_base64"`
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*d = DeadLetter(in.plain)
+	if in.RawText != nil {
+		d.Raw = []byte(*in.RawText)
+	} else {
+		d.Raw = in.RawBase64
+	}
+	return nil
+}
+
+// DeadLetterFilter selects dead letters; zero fields match everything.
+type DeadLetterFilter struct {
+	IDs             []int64
+	Feed            string
+	Stage           string
+	Symbol          string
+	Since           time.Time
+	Until           time.Time
+	IncludeRedriven bool
+	Limit           int
+}
+
+// Match reports whether d passes the filter.
+func (f DeadLetterFilter) Match(d DeadLetter) bool {
+	if len(f.IDs) > 0 {
+		found := false
+		for _, id := range f.IDs {
+			if id == d.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	switch {
+	case f.Feed != "" && f.Feed != d.Feed,
+		f.Stage != "" && f.Stage != d.Stage,
+		f.Symbol != "" && f.Symbol != d.Symbol,
+		!f.Since.IsZero() && d.ReceivedAt.Before(f.Since),
+		!f.Until.IsZero() && !d.ReceivedAt.Before(f.Until),
+		!f.IncludeRedriven && d.RedrivenAt != nil:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxScale is the largest number of fractional digits a Decimal carries.
+const maxScale = 18
+
+// maxDigits is the most digits an int64 coefficient can have.
+const maxDigits = 19
+
+// Decimal is a fixed-point number: coef * 10^-scale. It round-trips decimal
+// literals exactly, unlike float64. The zero value is 0.
+type Decimal struct {
+	coef  int64
+	scale uint8
+}
+
+// NewDecimal returns coef * 10^-scale.
+func NewDecimal(coef int64, scale int) Decimal {
+	if scale < 0 {
+		for ; scale < 0; scale++ {
+			coef *= 10
+		}
+	}
+	return Decimal{coef: coef, scale: uint8(min(scale, maxScale))}
+}
+
+// ParseDecimal parses a plain or exponent decimal literal such as "-12.3400"
+// or "1.5e-3". Trailing fractional zeros are kept as significant scale.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Decimal{}, fmt.Errorf("empty decimal")
+	}
+	literal := s
+
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", literal)
+		}
+		exp = e
+		s = s[:i]
+	}
+
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", literal)
+	}
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	if digits == "" {
+		// Zero: any exponent is valid, it only sets the scale
+		return Decimal{scale: uint8(min(max(len(fracPart)-exp, 0), maxScale))}, nil
+	}
+	// Bound the exponent before padding with zeros: a larger one leaves more
+	// than maxDigits integer digits, a smaller one more than maxScale
+	// fractional digits even once trailing zeros are dropped
+	if exp > maxDigits+len(fracPart)-len(digits) {
+		return Decimal{}, fmt.Errorf("decimal %q out of range", literal)
+	}
+	if exp < len(fracPart)-len(digits)-maxScale {
+		return Decimal{}, fmt.Errorf("decimal %q exceeds %d fractional digits", literal, maxScale)
+	}
+	scale := len(fracPart) - exp
+	if scale < 0 {
+		digits += strings.Repeat("0", -scale)
+		scale = 0
+	}
+	// Drop excess precision zeros so long literals still fit
+	for scale > maxScale && strings.HasSuffix(digits, "0") {
+		digits = digits[:len(digits)-1]
+		scale--
+	}
+	if scale > maxScale {
+		return Decimal{}, fmt.Errorf("decimal %q exceeds %d fractional digits", literal, maxScale)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", literal)
+		}
+	}
+	coef, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("decimal %q out of range", literal)
+	}
+	if neg {
+		coef = -coef
+	}
+	return Decimal{coef: coef, scale: uint8(scale)}, nil
+}
+
+// DecimalFromFloat converts using the shortest representation that
+// round-trips f, so 1.10005 becomes exactly 1.10005.
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("cannot represent %v as decimal", f)
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// DecimalFrom converts a decoded JSON value (json.Number, float64, string or
+// Decimal) to a Decimal.
+func DecimalFrom(v any) (Decimal, bool) {
+	var (
+		d   Decimal
+		err error
+	)
+	switch t := v.(type) {
+	case Decimal:
+		return t, true
+	case json.Number:
+		d, err = ParseDecimal(t.String())
+	case float64:
+		d, err = DecimalFromFloat(t)
+	case int64:
+		d = NewDecimal(t, 0)
+	case int:
+		d = NewDecimal(int64(t), 0)
+	case string:
+		d, err = ParseDecimal(t)
+	default:
+		return Decimal{}, false
+	}
+	return d, err == nil
+}
+
+func (d Decimal) Scale() int { return int(d.scale) }
+
+func (d Decimal) IsZero() bool { return d.coef == 0 }
+
+func (d Decimal) Sign() int {
+	switch {
+	case d.coef > 0:
+		return 1
+	case d.coef < 0:
+		return -1
+	}
+	return 0
+}
+
+// Float64 returns the nearest float64; use only for display or statistics.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+func (d Decimal) String() string {
+	neg := d.coef < 0
+	var digits string
+	if neg {
+		// avoid overflow on MinInt64
+		digits = strconv.FormatUint(uint64(-(d.coef+1))+1, 10)
+	} else {
+		digits = strconv.FormatInt(d.coef, 10)
+	}
+	if d.scale > 0 {
+		if len(digits) <= int(d.scale) {
+			digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+		}
+		cut := len(digits) - int(d.scale)
+		digits = digits[:cut] + "." + digits[cut:]
+	}
+	if neg {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON writes the decimal as an unquoted JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if unq, err := strconv.Unquote(s); err == nil {
+		s = unq
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Value stores the decimal as its exact text, suitable for NUMERIC columns.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		return d.UnmarshalJSON(v)
+	case string:
+		return d.UnmarshalJSON([]byte(v))
+	case int64:
+		*d = NewDecimal(v, 0)
+		return nil
+	case float64:
+		dec, err := DecimalFromFloat(v)
+		*d = dec
+		return err
+	}
+	return fmt.Errorf("cannot scan %T into Decimal", src)
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"math"
+	"math/big"
+)
+
+var (
+	bigTen    = big.NewInt(10)
+	bigMaxI64 = big.NewInt(math.MaxInt64)
+	bigMinI64 = big.NewInt(math.MinInt64)
+)
+
+func (d Decimal) big() *big.Int { return big.NewInt(d.coef) }
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+// roundBig divides coef by 10^drop rounding half away from zero.
+func roundBig(coef *big.Int, drop int) *big.Int {
+	if drop <= 0 {
+		return coef
+	}
+	div := pow10(drop)
+	q, r := new(big.Int).QuoRem(coef, div, new(big.Int))
+	r.Abs(r).Mul(r, big.NewInt(2))
+	if r.Cmp(div) >= 0 {
+		if coef.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// fromBig builds a Decimal, shedding fractional digits (rounded) until the
+// coefficient fits in int64 and the scale is within maxScale.
+func fromBig(coef *big.Int, scale int) Decimal {
+	if scale > maxScale {
+		coef = roundBig(coef, scale-maxScale)
+		scale = maxScale
+	}
+	for scale > 0 && (coef.Cmp(bigMaxI64) > 0 || coef.Cmp(bigMinI64) < 0) {
+		coef = roundBig(coef, 1)
+		scale--
+	}
+	switch {
+	case coef.Cmp(bigMaxI64) > 0:
+		return Decimal{coef: math.MaxInt64}
+	case coef.Cmp(bigMinI64) < 0:
+		return Decimal{coef: math.MinInt64}
+	}
+	return Decimal{coef: coef.Int64(), scale: uint8(scale)}
+}
+
+// align returns both coefficients at the larger of the two scales.
+func align(a, b Decimal) (*big.Int, *big.Int, int) {
+	ac, bc := a.big(), b.big()
+	switch {
+	case a.scale > b.scale:
+		bc.Mul(bc, pow10(int(a.scale-b.scale)))
+		return ac, bc, int(a.scale)
+	case b.scale > a.scale:
+		ac.Mul(ac, pow10(int(b.scale-a.scale)))
+	}
+	return ac, bc, int(b.scale)
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	a, b, scale := align(d, o)
+	return fromBig(a.Add(a, b), scale)
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	a, b, scale := align(d, o)
+	return fromBig(a.Sub(a, b), scale)
+}
+
+// Mul returns the exact product (scale is the sum of both scales, capped).
+func (d Decimal) Mul(o Decimal) Decimal {
+	p := d.big()
+	p.Mul(p, o.big())
+	return fromBig(p, int(d.scale)+int(o.scale))
+}
+
+// Div returns d/o rounded half away from zero to scale fractional digits.
+// Division by zero returns d unchanged and false.
+func (d Decimal) Div(o Decimal, scale int) (Decimal, bool) {
+	if o.coef == 0 {
+		return d, false
+	}
+	// d/o = (dc * 10^(os - ds + scale + 1)) / oc * 10^-(scale+1)
+	num := d.big()
+	shift := int(o.scale) - int(d.scale) + scale + 1
+	den := o.big()
+	if shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	q := new(big.Int).Quo(num, den)
+	return fromBig(roundBig(q, 1), scale), true
+}
+
+// Round rounds half away from zero to scale fractional digits. Values with
+// fewer digits are padded, so the result always has exactly that scale.
+func (d Decimal) Round(scale int) Decimal {
+	if scale < 0 {
+		scale = 0
+	}
+	if scale >= int(d.scale) {
+		c := d.big()
+		return fromBig(c.Mul(c, pow10(scale-int(d.scale))), scale)
+	}
+	return fromBig(roundBig(d.big(), int(d.scale)-scale), scale)
+}
+
+// RoundToTick rounds to the nearest multiple of tick. A zero tick is a no-op.
+func (d Decimal) RoundToTick(tick Decimal) Decimal {
+	if tick.coef <= 0 {
+		return d
+	}
+	steps, _ := d.Div(tick, 0)
+	return steps.Mul(tick).Round(max(int(tick.scale), 0))
+}
+
+// Cmp returns -1, 0 or 1 comparing d to o numerically.
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, _ := align(d, o)
+	return a.Cmp(b)
+}
@@ -0,0 +1,144 @@
+package models
+
+import "testing"
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "-12.3400", want: "-12.3400"},
+		{in: "+7", want: "7"},
+		{in: ".5", want: "0.5"},
+		{in: "1.5e-3", want: "0.0015"},
+		{in: "1.5E3", want: "1500"},
+		{in: "0", want: "0"},
+		{in: "0.00", want: "0.00"},
+		{in: "0e100", want: "0"},
+		{in: "0.0e-100", want: "0.000000000000000000"},
+		{in: "-0e5", want: "0"},
+		{in: "9e18", want: "9000000000000000000"},
+		{in: "1e19", wantErr: true},
+		{in: "1e999999", wantErr: true},
+		{in: "1e-18", want: "0.000000000000000001"},
+		{in: "1e-19", wantErr: true},
+		{in: "1.000e-16", want: "0.000000000000000100"},
+		{in: "0.1234567890123456789", wantErr: true},
+		{in: "9223372036854775807", want: "9223372036854775807"},
+		{in: "9223372036854775808", wantErr: true},
+		{in: "", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "12a", wantErr: true},
+		{in: "1e", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDecimal(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q) = %s, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewDecimalNegativeScale(t *testing.T) {
+	tests := []struct {
+		coef  int64
+		scale int
+		want  string
+	}{
+		{coef: 5, scale: -2, want: "500"},
+		{coef: -12, scale: -1, want: "-120"},
+		{coef: 5, scale: 0, want: "5"},
+		{coef: 5, scale: 30, want: "0.000000000000000005"},
+	}
+	for _, tt := range tests {
+		if got := NewDecimal(tt.coef, tt.scale).String(); got != tt.want {
+			t.Errorf("NewDecimal(%d, %d) = %s, want %s", tt.coef, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func mustDecimal(t *testing.T, s string) Decimal {
+	t.Helper()
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatalf("ParseDecimal(%q): %v", s, err)
+	}
+	return d
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		in    string
+		scale int
+		want  string
+	}{
+		{in: "1.2345", scale: 2, want: "1.23"},
+		{in: "1.235", scale: 2, want: "1.24"},
+		{in: "-1.235", scale: 2, want: "-1.24"},
+		{in: "1.5", scale: 4, want: "1.5000"},
+		{in: "2.5", scale: 0, want: "3"},
+		{in: "2.5", scale: -1, want: "3"}, // negative scale rounds to an integer
+		{in: "-0.4", scale: 0, want: "0"},
+	}
+	for _, tt := range tests {
+		if got := mustDecimal(t, tt.in).Round(tt.scale).String(); got != tt.want {
+			t.Errorf("%s.Round(%d) = %s, want %s", tt.in, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalRoundToTick(t *testing.T) {
+	tests := []struct {
+		in, tick, want string
+	}{
+		{in: "100.07", tick: "0.05", want: "100.05"},
+		{in: "100.075", tick: "0.05", want: "100.10"},
+		{in: "-100.075", tick: "0.05", want: "-100.10"},
+		{in: "100.1", tick: "0.05", want: "100.10"},
+		{in: "1.10004", tick: "0.00005", want: "1.10005"},
+		{in: "1234", tick: "5", want: "1235"},
+		{in: "100.07", tick: "0", want: "100.07"}, // zero tick is a no-op
+	}
+	for _, tt := range tests {
+		got := mustDecimal(t, tt.in).RoundToTick(mustDecimal(t, tt.tick))
+		if got.String() != tt.want {
+			t.Errorf("%s.RoundToTick(%s) = %s, want %s", tt.in, tt.tick, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalDiv(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		scale  int
+		want   string
+		wantOK bool
+	}{
+		{a: "1", b: "3", scale: 4, want: "0.3333", wantOK: true},
+		{a: "2", b: "3", scale: 4, want: "0.6667", wantOK: true},
+		{a: "-2", b: "3", scale: 4, want: "-0.6667", wantOK: true},
+		{a: "10.5", b: "0.25", scale: 0, want: "42", wantOK: true},
+		{a: "0.001", b: "1000", scale: 2, want: "0.00", wantOK: true},
+		{a: "7.25", b: "0", scale: 2, want: "7.25", wantOK: false},
+		{a: "7.25", b: "0.000", scale: 2, want: "7.25", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := mustDecimal(t, tt.a).Div(mustDecimal(t, tt.b), tt.scale)
+		if ok != tt.wantOK || got.String() != tt.want {
+			t.Errorf("%s.Div(%s, %d) = %s, %v, want %s, %v", tt.a, tt.b, tt.scale, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Instrument is one row of the instrument master.
+type Instrument struct {
+	Symbol     string     `json:"symbol"`
+	Exchange   string     `json:"exchange"`
+	AssetClass string     `json:"asset_class"`
+	TickSize   Decimal    `json:"tick_size"`
+	LotSize    Decimal    `json:"lot_size"`
+	Precision  int        `json:"precision"`
+	Expiry     *time.Time `json:"expiry,omitempty"`
+	Underlying string     `json:"underlying,omitempty"`
+	Strike     *Decimal   `json:"strike,omitempty"`
+	Right      string     `json:"right,omitempty"`
+	Currency   string     `json:"currency"`
+	Active     bool       `json:"active"`
+	UpdatedAt  time.Time  `json:"updated_at,omitzero"`
+}
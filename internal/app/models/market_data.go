@@ -3,10 +3,33 @@ package models
 import "fmt"
 
 type MarketData struct {
-	Name      string                 `json:"name"`
-	Timestamp int64                  `json:"timestamp"`
-	Exchange  string                 `json:"exchange"`
-	Data      map[string]interface{} `json:"data"`
+	Name      string   `json:"name"`
+	Timestamp int64    `json:"timestamp"`
+	Exchange  string   `json:"exchange"`
+	Feed      string   `json:"feed"`
+	Seq       int64    `json:"seq,omitempty"`
+	Kind      TickKind `json:"kind,omitempty"`
+
+	// Typed views; any combination may be set (e.g. a quote with last trade).
+	Quote *Quote `json:"quote,omitempty"`
+	Trade *Trade `json:"trade,omitempty"`
+	Bar   *Bar   `json:"bar,omitempty"`
+	Depth *Depth `json:"depth,omitempty"`
+
+	// Option is set for option tradingsymbols.
+	Option *OptionContract `json:"option,omitempty"`
+
+	// Data is the raw vendor payload, kept as an escape hatch.
+	Data map[string]interface{} `json:"data"`
+
+	// Offset is the tick's position in the write-ahead log; zero when the
+	// tick was not logged.
+	Offset int64 `json:"-"`
+
+	// Alias marks a copy of a tick republished under a continuous alias. It
+	// is cached and broadcast but not stored, since the contract's own tick
+	// already is.
+	Alias bool `json:"-"`
 }
 
 func (m *MarketData) Validate() error {
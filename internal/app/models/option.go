@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// OptionRight is the call/put side of an option, in NSE notation.
+type OptionRight string
+
+const (
+	Call OptionRight = "CE"
+	Put  OptionRight = "PE"
+)
+
+// OptionContract describes an option tradingsymbol such as NIFTY25DEC24000CE.
+type OptionContract struct {
+	Underlying string      `json:"underlying"`
+	Expiry     time.Time   `json:"expiry"`
+	Strike     Decimal     `json:"strike"`
+	Right      OptionRight `json:"right"`
+}
+
+// ExpiryDate is the expiry as YYYY-MM-DD, the form used in chain keys.
+func (o OptionContract) ExpiryDate() string {
+	return o.Expiry.Format("2006-01-02")
+}
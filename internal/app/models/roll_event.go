@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RollEvent records a switch of the active contract behind a continuous alias.
+type RollEvent struct {
+	Root     string    `json:"root"`
+	Alias    string    `json:"alias"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Rule     string    `json:"rule"`
+	Reason   string    `json:"reason"`
+	RolledAt time.Time `json:"rolled_at"`
+}
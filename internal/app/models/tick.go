@@ -0,0 +1,226 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// TickKind identifies the primary typed view of a MarketData record.
+type TickKind string
+
+const (
+	KindRaw   TickKind = "raw"
+	KindQuote TickKind = "quote"
+	KindTrade TickKind = "trade"
+	KindBar   TickKind = "bar"
+	KindDepth TickKind = "depth"
+)
+
+type Quote struct {
+	Bid     Decimal  `json:"bid"`
+	Ask     Decimal  `json:"ask"`
+	BidSize *Decimal `json:"bid_size,omitempty"`
+	AskSize *Decimal `json:"ask_size,omitempty"`
+}
+
+type Trade struct {
+	Price Decimal  `json:"price"`
+	Qty   *Decimal `json:"qty,omitempty"`
+	Side  string   `json:"side,omitempty"` // "buy" or "sell"
+}
+
+type Bar struct {
+	Interval string   `json:"interval,omitempty"`
+	Open     Decimal  `json:"open"`
+	High     Decimal  `json:"high"`
+	Low      Decimal  `json:"low"`
+	Close    Decimal  `json:"close"`
+	Volume   *Decimal `json:"volume,omitempty"`
+}
+
+type Level struct {
+	Price  Decimal `json:"price"`
+	Size   Decimal `json:"size"`
+	Orders int     `json:"orders,omitempty"`
+}
+
+type Depth struct {
+	Bids []Level `json:"bids,omitempty"`
+	Asks []Level `json:"asks,omitempty"`
+}
+
+// Field aliases recognised when deriving typed views from raw payloads.
+// One-letter vendor keys are too ambiguous to match globally; mapping feeds
+// rename them with aliases instead.
+var (
+	bidKeys     = []string{"bid", "bid_price", "bidPrice", "best_bid", "bestBid"}
+	askKeys     = []string{"ask", "ask_price", "askPrice", "best_ask", "bestAsk", "offer"}
+	bidSizeKeys = []string{"bid_size", "bidSize", "bid_qty", "bidQty", "bid_quantity"}
+	askSizeKeys = []string{"ask_size", "askSize", "ask_qty", "askQty", "ask_quantity"}
+	priceKeys   = []string{"ltp", "last", "last_price", "lastPrice", "price"}
+	qtyKeys     = []string{"qty", "last_qty", "lastQty", "ltq", "quantity", "size"}
+	sideKeys    = []string{"side", "aggressor", "taker_side"}
+)
+
+// Payload returns the inner vendor payload: Data["data"] when present,
+// otherwise Data itself.
+func (m *MarketData) Payload() map[string]interface{} {
+	if inner, ok := m.Data["data"].(map[string]interface{}); ok {
+		return inner
+	}
+	return m.Data
+}
+
+// Classify fills the typed views that the decoder did not set by looking for
+// well-known field names in the raw payload, and sets Kind. Raw data is left
+// untouched as an escape hatch.
+func (m *MarketData) Classify() {
+	p := m.Payload()
+
+	if m.Quote == nil {
+		bid, okB := pick(p, bidKeys)
+		ask, okA := pick(p, askKeys)
+		if okB && okA {
+			m.Quote = &Quote{Bid: bid, Ask: ask}
+			if v, ok := pick(p, bidSizeKeys); ok {
+				m.Quote.BidSize = &v
+			}
+			if v, ok := pick(p, askSizeKeys); ok {
+				m.Quote.AskSize = &v
+			}
+		}
+	}
+
+	if m.Trade == nil {
+		if price, ok := pick(p, priceKeys); ok {
+			m.Trade = &Trade{Price: price, Side: pickSide(p)}
+			if v, ok := pick(p, qtyKeys); ok {
+				m.Trade.Qty = &v
+			}
+		}
+	}
+
+	if m.Bar == nil {
+		o, ok1 := pick(p, []string{"open"})
+		h, ok2 := pick(p, []string{"high"})
+		l, ok3 := pick(p, []string{"low"})
+		c, ok4 := pick(p, []string{"close"})
+		if ok1 && ok2 && ok3 && ok4 {
+			m.Bar = &Bar{Open: o, High: h, Low: l, Close: c}
+			if v, ok := pick(p, []string{"volume"}); ok {
+				m.Bar.Volume = &v
+			}
+			if iv, ok := p["interval"].(string); ok {
+				m.Bar.Interval = iv
+			}
+		}
+	}
+
+	if m.Depth == nil {
+		bids, okB := levels(p["bids"])
+		asks, okA := levels(p["asks"])
+		if okB || okA {
+			m.Depth = &Depth{Bids: bids, Asks: asks}
+		}
+	}
+
+	if m.Kind == "" {
+		switch {
+		case m.Depth != nil:
+			m.Kind = KindDepth
+		case m.Bar != nil:
+			m.Kind = KindBar
+		case m.Quote != nil:
+			m.Kind = KindQuote
+		case m.Trade != nil:
+			m.Kind = KindTrade
+		default:
+			m.Kind = KindRaw
+		}
+	}
+}
+
+// Typed returns the typed views as JSON, or nil for raw records.
+func (m *MarketData) Typed() []byte {
+	if m.Quote == nil && m.Trade == nil && m.Bar == nil && m.Depth == nil {
+		return nil
+	}
+	b, _ := json.Marshal(struct {
+		Quote *Quote `json:"quote,omitempty"`
+		Trade *Trade `json:"trade,omitempty"`
+		Bar   *Bar   `json:"bar,omitempty"`
+		Depth *Depth `json:"depth,omitempty"`
+	}{m.Quote, m.Trade, m.Bar, m.Depth})
+	return b
+}
+
+// Price is the trade price, the quote mid or the bar close, in that order.
+func (m *MarketData) Price() (Decimal, bool) {
+	switch {
+	case m.Trade != nil:
+		return m.Trade.Price, true
+	case m.Quote != nil:
+		return m.Quote.Bid.Add(m.Quote.Ask).Div(NewDecimal(2, 0), max(m.Quote.Bid.Scale(), m.Quote.Ask.Scale())+1)
+	case m.Bar != nil:
+		return m.Bar.Close, true
+	}
+	return Decimal{}, false
+}
+
+func pick(p map[string]interface{}, keys []string) (Decimal, bool) {
+	for _, k := range keys {
+		if v, ok := p[k]; ok {
+			if d, ok := DecimalFrom(v); ok {
+				return d, true
+			}
+		}
+	}
+	return Decimal{}, false
+}
+
+func pickSide(p map[string]interface{}) string {
+	for _, k := range sideKeys {
+		if s, ok := p[k].(string); ok {
+			switch strings.ToLower(s) {
+			case "b", "buy", "bid":
+				return "buy"
+			case "s", "sell", "ask", "offer":
+				return "sell"
+			}
+		}
+	}
+	return ""
+}
+
+// levels accepts [[price,size],...] or [{"price":..,"size":..},...].
+func levels(v any) ([]Level, bool) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := make([]Level, 0, len(arr))
+	for _, e := range arr {
+		switch lv := e.(type) {
+		case []interface{}:
+			if len(lv) < 2 {
+				continue
+			}
+			price, ok1 := DecimalFrom(lv[0])
+			size, ok2 := DecimalFrom(lv[1])
+			if ok1 && ok2 {
+				out = append(out, Level{Price: price, Size: size})
+			}
+		case map[string]interface{}:
+			price, ok1 := pick(lv, []string{"price", "p"})
+			size, ok2 := pick(lv, []string{"size", "qty", "quantity", "s", "q"})
+			if ok1 && ok2 {
+				l := Level{Price: price, Size: size}
+				if n, ok := DecimalFrom(lv["orders"]); ok {
+					l.Orders = int(n.Float64())
+				}
+				out = append(out, l)
+			}
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,248 @@
+package backpressure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Policy decides what Put does when the ingest channel is full.
+type Policy string
+
+const (
+	// Block waits for room, stalling the socket reader (legacy behaviour).
+	Block Policy = "block"
+	// DropOldest evicts the oldest queued tick to make room.
+	DropOldest Policy = "drop-oldest"
+	// DropNewest discards the incoming tick.
+	DropNewest Policy = "drop-newest"
+	// Conflate holds only the latest tick per symbol until there is room.
+	Conflate Policy = "conflate"
+	// Spill appends overflow to a local disk queue and drains it in order.
+	Spill Policy = "spill"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case Block, DropOldest, DropNewest, Conflate, Spill:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown backpressure policy %q", s)
+}
+
+// Queue is the bounded channel between the feeds and the processing stages.
+// Put never blocks the caller except under the Block policy.
+type Queue struct {
+	ch     chan models.MarketData
+	policy Policy
+	logger *logrus.Logger
+
+	mu       sync.Mutex
+	order    []string                     // Conflate: symbols in arrival order
+	latest   map[string]models.MarketData // Conflate: newest tick per symbol
+	spool    *spool                       // Spill
+	inflight bool                         // an overflow tick is being handed to ch
+	wake     chan struct{}
+}
+
+// New builds a queue holding capacity ticks in memory. spillDir is only used
+// by the Spill policy.
+func New(policy Policy, capacity int, spillDir string) (*Queue, error) {
+	q := &Queue{
+		ch:     make(chan models.MarketData, capacity),
+		policy: policy,
+		logger: logger.GetLogger(),
+		latest: make(map[string]models.MarketData),
+		wake:   make(chan struct{}, 1),
+	}
+	if policy == Spill {
+		s, err := openSpool(spillDir)
+		if err != nil {
+			return nil, err
+		}
+		q.spool = s
+		if s.len() > 0 {
+			q.logger.Info(fmt.Sprintf("Resuming %d spilled ticks from %s", s.len(), s.path))
+		}
+	}
+	return q, nil
+}
+
+// C is the consumer side of the queue. Producers that must never lose a
+// tick (e.g. gap backfill) may also send on it directly.
+func (q *Queue) C() chan models.MarketData {
+	return q.ch
+}
+
+// Put enqueues m according to the policy.
+func (q *Queue) Put(ctx context.Context, m models.MarketData) {
+	switch q.policy {
+	case DropNewest:
+		select {
+		case q.ch <- m:
+		default:
+			dropped(q.policy, m)
+		}
+	case DropOldest:
+		for {
+			select {
+			case q.ch <- m:
+				return
+			default:
+			}
+			select {
+			case old := <-q.ch:
+				dropped(q.policy, old)
+			default:
+			}
+		}
+	case Conflate:
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.backlog() == 0 && !q.inflight && q.trySend(m) {
+			return
+		}
+		if old, ok := q.latest[m.Name]; ok {
+			dropped(q.policy, old)
+		} else {
+			q.order = append(q.order, m.Name)
+		}
+		q.latest[m.Name] = m
+		q.signal()
+	case Spill:
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.backlog() == 0 && !q.inflight && q.trySend(m) {
+			return
+		}
+		if err := q.spool.push(m); err != nil {
+			q.logger.Error(fmt.Sprintf("Failed to spill tick for %s: %v", m.Name, err))
+			metrics.ErrorsTotal.WithLabelValues("spill").Inc()
+			dropped(q.policy, m)
+			return
+		}
+		q.signal()
+	default:
+		select {
+		case q.ch <- m:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Run drains conflated or spilled ticks into the channel as room frees up
+// and exports the queue depth.
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		for q.drainOne(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			if q.spool != nil {
+				q.spool.close()
+			}
+			return
+		case <-q.wake:
+		case <-ticker.C:
+			metrics.QueueDepth.WithLabelValues("ingest").Set(float64(len(q.ch)))
+			q.mu.Lock()
+			metrics.QueueDepth.WithLabelValues("overflow").Set(float64(q.backlog()))
+			q.mu.Unlock()
+		}
+	}
+}
+
+// drainOne moves the oldest overflow tick into the channel, blocking for
+// room; it reports false when there is nothing to drain.
+func (q *Queue) drainOne(ctx context.Context) bool {
+	q.mu.Lock()
+	m, ok := q.pop()
+	if ok {
+		q.inflight = true
+	}
+	q.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	select {
+	case q.ch <- m:
+	case <-ctx.Done():
+	}
+	q.mu.Lock()
+	q.inflight = false
+	q.mu.Unlock()
+	return ctx.Err() == nil
+}
+
+func (q *Queue) pop() (models.MarketData, bool) {
+	switch q.policy {
+	case Conflate:
+		if len(q.order) == 0 {
+			return models.MarketData{}, false
+		}
+		name := q.order[0]
+		q.order = q.order[1:]
+		m := q.latest[name]
+		delete(q.latest, name)
+		return m, true
+	case Spill:
+		m, ok, err := q.spool.pop()
+		if err != nil {
+			q.logger.Error(fmt.Sprintf("Failed to read spilled tick: %v", err))
+			metrics.ErrorsTotal.WithLabelValues("spill").Inc()
+		}
+		return m, ok
+	}
+	return models.MarketData{}, false
+}
+
+func (q *Queue) backlog() int {
+	if q.spool != nil {
+		return q.spool.len()
+	}
+	return len(q.order)
+}
+
+func (q *Queue) trySend(m models.MarketData) bool {
+	select {
+	case q.ch <- m:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func dropped(policy Policy, m models.MarketData) {
+	metrics.TicksDropped.WithLabelValues(string(policy), m.Name).Inc()
+}
+
+// Gauge exports the depth of a pipeline channel every second.
+func Gauge(ctx context.Context, name string, ch chan models.MarketData) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			metrics.QueueDepth.WithLabelValues(name).Set(float64(len(ch)))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package backpressure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// spool is an append-only file of JSON lines used as an overflow FIFO. It
+// is truncated whenever the reader catches up, and a file left over from a
+// previous run is drained on startup.
+type spool struct {
+	path string
+	w    *os.File
+	r    *os.File
+	br   *bufio.Reader
+	n    int
+}
+
+func openSpool(dir string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "ingest.spool")
+	w, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	r, err := os.Open(path)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	s := &spool{path: path, w: w, r: r, br: bufio.NewReader(r)}
+
+	// Count records left from a previous run
+	counter := bufio.NewReader(io.NewSectionReader(r, 0, 1<<62))
+	for {
+		line, err := counter.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			s.n++
+		}
+		if err != nil {
+			break
+		}
+	}
+	return s, nil
+}
+
+func (s *spool) len() int {
+	return s.n
+}
+
+func (s *spool) push(m models.MarketData) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.n++
+	return nil
+}
+
+func (s *spool) pop() (models.MarketData, bool, error) {
+	var m models.MarketData
+	if s.n == 0 {
+		return m, false, nil
+	}
+	line, err := s.br.ReadBytes('\n')
+	if err != nil {
+		return m, false, err
+	}
+	s.n--
+	if s.n == 0 {
+		if err := s.reset(); err != nil {
+			return m, false, err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return m, false, err
+	}
+	return m, true, nil
+}
+
+// reset truncates the drained file so it does not grow without bound.
+func (s *spool) reset() error {
+	if err := s.w.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.br.Reset(s.r)
+	return nil
+}
+
+func (s *spool) close() {
+	s.w.Close()
+	s.r.Close()
+}
@@ -0,0 +1,211 @@
+package bars
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Store persists bars, e.g. storage.Store.
+type Store interface {
+	UpsertCandles(ctx context.Context, candles []models.Candle) error
+}
+
+// Cache publishes bars to readers, e.g. storage.CacheService.
+type Cache interface {
+	PutCandles(ctx context.Context, candles []models.Candle, ttl time.Duration) error
+}
+
+type key struct {
+	symbol   string
+	interval string
+}
+
+// building is a bar still taking ticks. first and last are the timestamps of
+// the ticks that set open and close, so out-of-order ticks land correctly.
+type building struct {
+	models.Candle
+	first, last int64
+}
+
+// Aggregator builds OHLCV bars from processed ticks. A bar stays open until
+// Lateness after its end, so ticks that arrive a little late still count;
+// ticks for bars already closed, or outside the exchange's trading sessions,
+// are dropped and counted. Closed bars are upserted to the store, and bars
+// in progress are cached every second.
+type Aggregator struct {
+	intervals []Interval
+	sessions  Sessions
+	lateness  time.Duration
+	store     Store
+	cache     Cache
+	ttl       time.Duration
+	logger    *logrus.Logger
+
+	mu     sync.Mutex
+	open   map[key][]*building
+	closed map[key]int64 // end of the last closed bar
+	dirty  map[key]struct{}
+}
+
+func New(intervals []Interval, sessions Sessions, lateness time.Duration, store Store, cache Cache, ttl time.Duration) *Aggregator {
+	return &Aggregator{
+		intervals: intervals,
+		sessions:  sessions,
+		lateness:  lateness,
+		store:     store,
+		cache:     cache,
+		ttl:       ttl,
+		logger:    logger.GetLogger(),
+		open:      make(map[key][]*building),
+		closed:    make(map[key]int64),
+		dirty:     make(map[key]struct{}),
+	}
+}
+
+// Observe adds processed ticks to their bars.
+func (a *Aggregator) Observe(batch []models.MarketData) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, m := range batch {
+		px, ok := m.Price()
+		if !ok || m.Timestamp <= 0 {
+			continue
+		}
+		t := time.UnixMilli(m.Timestamp)
+		sessionOpen, sessionClose, ok := a.sessions.SessionBounds(m.Exchange, t)
+		if !ok {
+			metrics.OffSessionTicks.WithLabelValues(m.Exchange).Inc()
+			continue
+		}
+		for _, iv := range a.intervals {
+			start, end := iv.Bounds(sessionOpen, sessionClose, t)
+			k := key{m.Name, iv.Name}
+			if end.UnixMilli() <= a.closed[k] {
+				metrics.LateTicks.WithLabelValues(iv.Name).Inc()
+				continue
+			}
+			a.bar(k, m.Exchange, start.UnixMilli(), end.UnixMilli()).add(m.Timestamp, px, qty(m))
+			a.dirty[k] = struct{}{}
+		}
+	}
+}
+
+// bar returns the open bar of k starting at start, creating it if needed.
+func (a *Aggregator) bar(k key, exchange string, start, end int64) *building {
+	list := a.open[k]
+	i := sort.Search(len(list), func(i int) bool { return list[i].Start >= start })
+	if i < len(list) && list[i].Start == start {
+		return list[i]
+	}
+	b := &building{Candle: models.Candle{Symbol: k.symbol, Exchange: exchange, Interval: k.interval, Start: start, End: end}}
+	list = append(list, nil)
+	copy(list[i+1:], list[i:])
+	list[i] = b
+	a.open[k] = list
+	return b
+}
+
+func (b *building) add(ts int64, px models.Decimal, size *models.Decimal) {
+	if b.Ticks == 0 {
+		b.Open, b.High, b.Low, b.Close = px, px, px, px
+		b.first, b.last = ts, ts
+	} else {
+		if px.Cmp(b.High) > 0 {
+			b.High = px
+		}
+		if px.Cmp(b.Low) < 0 {
+			b.Low = px
+		}
+		if ts < b.first {
+			b.Open, b.first = px, ts
+		}
+		if ts >= b.last {
+			b.Close, b.last = px, ts
+		}
+	}
+	if size != nil {
+		v := *size
+		if b.Volume != nil {
+			v = b.Volume.Add(v)
+		}
+		b.Volume = &v
+	}
+	b.Ticks++
+}
+
+// Run closes due bars and publishes bar updates every second until ctx is
+// done; bars still open at shutdown are persisted as they are.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.flush(context.Background(), time.Time{})
+			return
+		case now := <-ticker.C:
+			a.flush(ctx, now)
+		}
+	}
+}
+
+// flush closes the bars that ended Lateness before now; a zero now closes
+// every bar.
+func (a *Aggregator) flush(ctx context.Context, now time.Time) {
+	a.mu.Lock()
+	var final, current []models.Candle
+	cutoff := now.Add(-a.lateness).UnixMilli()
+	for k, list := range a.open {
+		n := 0
+		for _, b := range list {
+			if !now.IsZero() && b.End > cutoff {
+				break
+			}
+			b.Final = true
+			final = append(final, b.Candle)
+			a.closed[k] = b.End
+			n++
+		}
+		if n == len(list) {
+			delete(a.open, k)
+		} else {
+			a.open[k] = list[n:]
+		}
+		if _, ok := a.dirty[k]; ok && n < len(list) {
+			current = append(current, list[len(list)-1].Candle)
+		}
+	}
+	clear(a.dirty)
+	a.mu.Unlock()
+
+	if len(final) > 0 {
+		if err := a.store.UpsertCandles(ctx, final); err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to store %d bars: %v", len(final), err))
+		}
+		metrics.BarsClosed.Add(float64(len(final)))
+	}
+	if updates := append(final, current...); len(updates) > 0 {
+		if err := a.cache.PutCandles(ctx, updates, a.ttl); err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to cache %d bars: %v", len(updates), err))
+		}
+	}
+}
+
+func qty(m models.MarketData) *models.Decimal {
+	switch {
+	case m.Trade != nil:
+		return m.Trade.Qty
+	case m.Bar != nil:
+		return m.Bar.Volume
+	}
+	return nil
+}
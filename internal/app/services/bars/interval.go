@@ -0,0 +1,71 @@
+package bars
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Interval is a bar length. Day bars span one trading session rather than
+// 24 hours.
+type Interval struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Intervals are the supported bar lengths, shortest first.
+var Intervals = []Interval{
+	{"1s", time.Second},
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+}
+
+// Lookup returns the interval called name.
+func Lookup(name string) (Interval, bool) {
+	for _, iv := range Intervals {
+		if iv.Name == name {
+			return iv, true
+		}
+	}
+	return Interval{}, false
+}
+
+// ParseIntervals reads a comma-separated list such as "1m,5m,1d".
+func ParseIntervals(spec string) ([]Interval, error) {
+	var out []Interval
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		iv, ok := Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown bar interval %q", name)
+		}
+		out = append(out, iv)
+	}
+	return out, nil
+}
+
+func (iv Interval) daily() bool {
+	return iv.Duration == 24*time.Hour
+}
+
+// Bounds returns the bar of iv containing t for the session [open, close):
+// bars are laid end to end from the open, the last one is cut at the close,
+// and a day bar covers the whole session.
+func (iv Interval) Bounds(open, close, t time.Time) (start, end time.Time) {
+	if iv.daily() {
+		return open, close
+	}
+	n := t.Sub(open) / iv.Duration
+	start = open.Add(n * iv.Duration)
+	end = start.Add(iv.Duration)
+	if end.After(close) {
+		end = close
+	}
+	return start, end
+}
@@ -0,0 +1,10 @@
+package bars
+
+import "time"
+
+// Sessions anchors bar boundaries, e.g. calendar.Calendar. SessionBounds
+// returns the trading session containing t on exchange; ok is false when
+// the exchange is closed at t.
+type Sessions interface {
+	SessionBounds(exchange string, t time.Time) (open, close time.Time, ok bool)
+}
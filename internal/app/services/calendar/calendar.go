@@ -0,0 +1,174 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // exchange time zones must resolve on hosts without zoneinfo
+)
+
+// Window is one trading session of a day as offsets from local midnight.
+// Close may exceed 24h for sessions that run overnight.
+type Window struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// Exchange holds the weekly sessions and holidays of one exchange. A session
+// is skipped when the local date it opens on is a holiday.
+type Exchange struct {
+	Code       string
+	Location   *time.Location
+	Weekly     [7][]Window
+	Holidays   map[string]struct{} // YYYY-MM-DD
+	AlwaysOpen bool
+}
+
+// Calendar answers market-hours questions by exchange code, the codes the
+// instrument master uses (nse, mcx, comex, forex, crypto, ...). Exchanges it
+// does not know, and a nil Calendar, are treated as always open.
+type Calendar struct {
+	exchanges map[string]*Exchange
+}
+
+// lookback covers sessions that opened on an earlier day and are still open.
+const lookback = 2
+
+// horizon bounds the search for the next open, e.g. across long holidays.
+const horizon = 31
+
+func (e *Exchange) session(day time.Time, w Window) (time.Time, time.Time) {
+	clock := func(d time.Duration) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, e.Location)
+	}
+	return clock(w.Open), clock(w.Close)
+}
+
+// sessions calls fn with each session opening on the local days from..to in
+// order until fn returns false.
+func (e *Exchange) sessions(t time.Time, from, to int, fn func(open, close time.Time) bool) {
+	local := t.In(e.Location)
+	for i := from; i <= to; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 0, 0, 0, 0, e.Location)
+		if _, ok := e.Holidays[day.Format(time.DateOnly)]; ok {
+			continue
+		}
+		for _, w := range e.Weekly[day.Weekday()] {
+			if open, close := e.session(day, w); !fn(open, close) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Calendar) lookup(exchange string) *Exchange {
+	if c == nil {
+		return nil
+	}
+	e := c.exchanges[strings.ToLower(exchange)]
+	if e == nil || e.AlwaysOpen {
+		return nil
+	}
+	return e
+}
+
+// Exchanges returns the known exchange codes.
+func (c *Calendar) Exchanges() []string {
+	if c == nil {
+		return nil
+	}
+	out := make([]string, 0, len(c.exchanges))
+	for code := range c.exchanges {
+		out = append(out, code)
+	}
+	return out
+}
+
+// AlwaysOpen reports whether exchange trades around the clock, which is
+// also the case for exchanges the calendar does not know.
+func (c *Calendar) AlwaysOpen(exchange string) bool {
+	return c.lookup(exchange) == nil
+}
+
+// IsOpen reports whether exchange is in a trading session at t.
+func (c *Calendar) IsOpen(exchange string, t time.Time) bool {
+	_, _, ok := c.SessionBounds(exchange, t)
+	return ok
+}
+
+// SessionBounds returns the session of exchange containing t; ok is false
+// when the exchange is closed at t. Always-open exchanges report the UTC day.
+func (c *Calendar) SessionBounds(exchange string, t time.Time) (open, close time.Time, ok bool) {
+	e := c.lookup(exchange)
+	if e == nil {
+		u := t.UTC()
+		open = time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, time.UTC)
+		return open, open.AddDate(0, 0, 1), true
+	}
+	e.sessions(t, -lookback, 0, func(o, cl time.Time) bool {
+		if !t.Before(o) && t.Before(cl) {
+			open, close, ok = o, cl, true
+			return false
+		}
+		return true
+	})
+	return open, close, ok
+}
+
+// NextOpen returns the first session open of exchange at or after t; ok is
+// false when none falls within a month. Always-open exchanges return t.
+func (c *Calendar) NextOpen(exchange string, t time.Time) (next time.Time, ok bool) {
+	e := c.lookup(exchange)
+	if e == nil {
+		return t, true
+	}
+	e.sessions(t, -lookback, horizon, func(o, _ time.Time) bool {
+		if !o.Before(t) {
+			next, ok = o, true
+			return false
+		}
+		return true
+	})
+	return next, ok
+}
+
+// Default returns the built-in calendar without holidays.
+func Default() *Calendar {
+	c := &Calendar{exchanges: make(map[string]*Exchange)}
+	for code, spec := range defaults {
+		e, err := spec.build(code, nil)
+		if err != nil {
+			panic(err)
+		}
+		c.exchanges[code] = e
+	}
+	return c
+}
+
+// Load returns the built-in calendar with the exchanges in the JSON file at
+// path merged over it; an empty path loads only the defaults.
+func Load(path string) (*Calendar, error) {
+	c := Default()
+	if path == "" {
+		return c, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]Spec
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for code, spec := range file {
+		code = strings.ToLower(code)
+		e, err := spec.build(code, c.exchanges[code])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, code, err)
+		}
+		c.exchanges[code] = e
+	}
+	return c, nil
+}
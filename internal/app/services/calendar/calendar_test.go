@@ -0,0 +1,152 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func loadTestCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	spec := `{
+		"nse":  {"holidays": ["2026-10-20"]},
+		"gift": {"holidays": ["2026-10-14"]}
+	}`
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return c
+}
+
+func TestSessionBounds(t *testing.T) {
+	c := loadTestCalendar(t)
+	tests := []struct {
+		name      string
+		exchange  string
+		at        string
+		wantOpen  bool
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:     "nse in session",
+			exchange: "nse", at: "2026-10-16T04:30:00Z", // Fri 10:00 IST
+			wantOpen: true, wantStart: "2026-10-16T03:45:00Z", wantEnd: "2026-10-16T10:00:00Z",
+		},
+		{name: "nse before open", exchange: "NSE", at: "2026-10-16T03:44:00Z"},
+		{name: "nse at close", exchange: "nse", at: "2026-10-16T10:00:00Z"},
+		{name: "nse saturday", exchange: "nse", at: "2026-10-17T04:30:00Z"},
+		{name: "nse holiday", exchange: "nse", at: "2026-10-20T04:30:00Z"},
+		{
+			name:     "overnight session after midnight",
+			exchange: "gift", at: "2026-10-13T19:30:00Z", // Wed 01:00 IST, Tue session
+			wantOpen: true, wantStart: "2026-10-13T01:00:00Z", wantEnd: "2026-10-13T21:15:00Z",
+		},
+		{name: "overnight session closed", exchange: "gift", at: "2026-10-13T21:30:00Z"},
+		{
+			name:     "friday session runs into saturday",
+			exchange: "gift", at: "2026-10-16T19:30:00Z", // Sat 01:00 IST
+			wantOpen: true, wantStart: "2026-10-16T01:00:00Z", wantEnd: "2026-10-16T21:15:00Z",
+		},
+		{name: "no saturday session", exchange: "gift", at: "2026-10-17T19:30:00Z"},
+		{
+			name:     "holiday keeps the previous overnight session",
+			exchange: "gift", at: "2026-10-13T20:00:00Z", // Wed 01:30 IST, Wed is a holiday
+			wantOpen: true, wantStart: "2026-10-13T01:00:00Z", wantEnd: "2026-10-13T21:15:00Z",
+		},
+		{name: "holiday session skipped", exchange: "gift", at: "2026-10-14T04:30:00Z"},
+		{
+			name:     "us equities before dst starts",
+			exchange: "usstock", at: "2026-03-06T15:00:00Z",
+			wantOpen: true, wantStart: "2026-03-06T14:30:00Z", wantEnd: "2026-03-06T21:00:00Z",
+		},
+		{
+			name:     "us equities after dst starts",
+			exchange: "usstock", at: "2026-03-09T15:00:00Z",
+			wantOpen: true, wantStart: "2026-03-09T13:30:00Z", wantEnd: "2026-03-09T20:00:00Z",
+		},
+		{
+			name:     "us equities open an hour earlier in utc",
+			exchange: "usstock", at: "2026-03-09T13:45:00Z",
+			wantOpen: true, wantStart: "2026-03-09T13:30:00Z", wantEnd: "2026-03-09T20:00:00Z",
+		},
+		{
+			name:     "overnight session across the dst change",
+			exchange: "comex", at: "2026-03-08T22:30:00Z", // Sun 18:30 EDT
+			wantOpen: true, wantStart: "2026-03-08T22:00:00Z", wantEnd: "2026-03-09T21:00:00Z",
+		},
+		{name: "comex closed before the sunday open", exchange: "comex", at: "2026-03-01T22:30:00Z"}, // Sun 17:30 EST
+		{
+			name:     "crypto always open",
+			exchange: "crypto", at: "2026-10-17T12:00:00Z",
+			wantOpen: true, wantStart: "2026-10-17T00:00:00Z", wantEnd: "2026-10-18T00:00:00Z",
+		},
+		{
+			name:     "unknown exchange always open",
+			exchange: "lse", at: "2026-10-18T23:59:00Z",
+			wantOpen: true, wantStart: "2026-10-18T00:00:00Z", wantEnd: "2026-10-19T00:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, close, ok := c.SessionBounds(tt.exchange, utc(tt.at))
+			if ok != tt.wantOpen {
+				t.Fatalf("SessionBounds(%s, %s) open = %v, want %v", tt.exchange, tt.at, ok, tt.wantOpen)
+			}
+			if c.IsOpen(tt.exchange, utc(tt.at)) != ok {
+				t.Errorf("IsOpen disagrees with SessionBounds")
+			}
+			if !ok {
+				return
+			}
+			if !open.Equal(utc(tt.wantStart)) || !close.Equal(utc(tt.wantEnd)) {
+				t.Errorf("session = %s - %s, want %s - %s", open.UTC().Format(time.RFC3339), close.UTC().Format(time.RFC3339), tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestNextOpen(t *testing.T) {
+	c := loadTestCalendar(t)
+	tests := []struct {
+		name     string
+		exchange string
+		at       string
+		want     string
+	}{
+		{name: "weekend", exchange: "nse", at: "2026-10-17T04:30:00Z", want: "2026-10-19T03:45:00Z"},
+		{name: "skips holiday", exchange: "nse", at: "2026-10-19T11:00:00Z", want: "2026-10-21T03:45:00Z"},
+		{name: "at the open", exchange: "nse", at: "2026-10-19T03:45:00Z", want: "2026-10-19T03:45:00Z"},
+		{name: "across dst weekend", exchange: "usstock", at: "2026-03-06T21:00:00Z", want: "2026-03-09T13:30:00Z"},
+		{name: "always open", exchange: "crypto", at: "2026-10-17T04:30:00Z", want: "2026-10-17T04:30:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := c.NextOpen(tt.exchange, utc(tt.at))
+			if !ok || !next.Equal(utc(tt.want)) {
+				t.Errorf("NextOpen(%s, %s) = %s, %v, want %s", tt.exchange, tt.at, next.UTC().Format(time.RFC3339), ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilCalendarAlwaysOpen(t *testing.T) {
+	var c *Calendar
+	if !c.AlwaysOpen("nse") || !c.IsOpen("nse", utc("2026-10-17T04:30:00Z")) {
+		t.Error("nil calendar should treat every exchange as open")
+	}
+}
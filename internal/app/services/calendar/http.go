@@ -0,0 +1,56 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Status is the market-hours snapshot reported for one exchange.
+type Status struct {
+	Exchange     string    `json:"exchange"`
+	Open         bool      `json:"open"`
+	SessionOpen  time.Time `json:"session_open,omitzero"`
+	SessionClose time.Time `json:"session_close,omitzero"`
+	NextOpen     time.Time `json:"next_open,omitzero"`
+}
+
+// Status reports whether exchange is open at t, with the current session or
+// the next open.
+func (c *Calendar) Status(exchange string, t time.Time) Status {
+	s := Status{Exchange: exchange}
+	s.SessionOpen, s.SessionClose, s.Open = c.SessionBounds(exchange, t)
+	if !s.Open {
+		s.NextOpen, _ = c.NextOpen(exchange, t)
+	}
+	return s
+}
+
+// NewHandler serves GET /calendar?exchange=nse&at=RFC3339; without exchange
+// every known exchange is listed, and at defaults to now.
+func NewHandler(c *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		at := time.Now()
+		if v := q.Get("at"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "invalid at", http.StatusBadRequest)
+				return
+			}
+			at = t
+		}
+		exchanges := c.Exchanges()
+		if e := q.Get("exchange"); e != "" {
+			exchanges = []string{e}
+		}
+		sort.Strings(exchanges)
+		list := make([]Status, 0, len(exchanges))
+		for _, e := range exchanges {
+			list = append(list, c.Status(e, at))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Spec is the file form of an exchange, e.g.
+//
+//	{"timezone": "Asia/Kolkata", "sessions": {"mon-fri": ["09:15-15:30"]},
+//	 "holidays": ["2026-01-26"]}
+//
+// Fields left out keep the built-in values. A session whose close is not
+// after its open ends on the following day.
+type Spec struct {
+	Timezone   string              `json:"timezone,omitempty"`
+	Sessions   map[string][]string `json:"sessions,omitempty"`
+	Holidays   []string            `json:"holidays,omitempty"`
+	AlwaysOpen bool                `json:"always_open,omitempty"`
+}
+
+var defaults = map[string]Spec{
+	"nse":     {Timezone: "Asia/Kolkata", Sessions: map[string][]string{"mon-fri": {"09:15-15:30"}}},
+	"cepe":    {Timezone: "Asia/Kolkata", Sessions: map[string][]string{"mon-fri": {"09:15-15:30"}}},
+	"mcx":     {Timezone: "Asia/Kolkata", Sessions: map[string][]string{"mon-fri": {"09:00-23:30"}}},
+	"gift":    {Timezone: "Asia/Kolkata", Sessions: map[string][]string{"mon-fri": {"06:30-02:45"}}},
+	"comex":   {Timezone: "America/New_York", Sessions: map[string][]string{"sun-thu": {"18:00-17:00"}}},
+	"other":   {Timezone: "America/New_York", Sessions: map[string][]string{"sun-thu": {"18:00-17:00"}}},
+	"forex":   {Timezone: "America/New_York", Sessions: map[string][]string{"sun-thu": {"17:00-17:00"}}},
+	"usstock": {Timezone: "America/New_York", Sessions: map[string][]string{"mon-fri": {"09:30-16:00"}}},
+	"crypto":  {AlwaysOpen: true},
+}
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func (s Spec) build(code string, base *Exchange) (*Exchange, error) {
+	e := &Exchange{Code: code, Location: time.UTC, Holidays: make(map[string]struct{})}
+	if base != nil {
+		e.Location, e.Weekly, e.AlwaysOpen = base.Location, base.Weekly, base.AlwaysOpen
+		for d := range base.Holidays {
+			e.Holidays[d] = struct{}{}
+		}
+	}
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		e.Location = loc
+	}
+	if s.AlwaysOpen {
+		e.AlwaysOpen = true
+	}
+	if s.Sessions != nil {
+		e.Weekly = [7][]Window{}
+		for days, windows := range s.Sessions {
+			set, err := parseDays(days)
+			if err != nil {
+				return nil, err
+			}
+			for _, spec := range windows {
+				w, err := parseWindow(spec)
+				if err != nil {
+					return nil, err
+				}
+				for _, d := range set {
+					e.Weekly[d] = append(e.Weekly[d], w)
+				}
+			}
+		}
+	}
+	for _, h := range s.Holidays {
+		if _, err := time.Parse(time.DateOnly, h); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q", h)
+		}
+		e.Holidays[h] = struct{}{}
+	}
+	return e, nil
+}
+
+// parseDays reads "mon-fri", "sun-thu", "sat", "mon,wed" or "daily".
+func parseDays(spec string) ([]time.Weekday, error) {
+	var out []time.Weekday
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		part = strings.TrimSpace(part)
+		if part == "daily" {
+			part = "sun-sat"
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		a, b := indexOf(from), indexOf(to)
+		if a < 0 || b < 0 {
+			return nil, fmt.Errorf("invalid days %q", spec)
+		}
+		for d := a; ; d = (d + 1) % 7 {
+			out = append(out, time.Weekday(d))
+			if d == b {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseWindow reads "HH:MM-HH:MM".
+func parseWindow(spec string) (Window, error) {
+	from, to, ok := strings.Cut(spec, "-")
+	open, err1 := time.Parse("15:04", strings.TrimSpace(from))
+	close, err2 := time.Parse("15:04", strings.TrimSpace(to))
+	if !ok || err1 != nil || err2 != nil {
+		return Window{}, fmt.Errorf("invalid session %q", spec)
+	}
+	w := Window{
+		Open:  time.Duration(open.Hour())*time.Hour + time.Duration(open.Minute())*time.Minute,
+		Close: time.Duration(close.Hour())*time.Hour + time.Duration(close.Minute())*time.Minute,
+	}
+	if w.Close <= w.Open {
+		w.Close += 24 * time.Hour
+	}
+	return w, nil
+}
+
+func indexOf(day string) int {
+	for i, d := range dayNames {
+		if d == day {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+package deadletter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// FileSink appends dead letters to a file as JSON lines. IDs are line
+// numbers. The file itself is never rewritten: re-driven IDs are appended to
+// a <path>.redriven sidecar and applied when listing.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+	next int64
+}
+
+func OpenFile(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSink{path: path, f: f, next: 1}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		s.next++
+	}
+	return s, nil
+}
+
+func (s *FileSink) PutDeadLetter(_ context.Context, d models.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.ID = s.next
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.next++
+	return nil
+}
+
+func (s *FileSink) ListDeadLetters(_ context.Context, f models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	r, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	redriven, err := s.redriven()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []models.DeadLetter
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		var d models.DeadLetter
+		if err := json.Unmarshal(sc.Bytes(), &d); err != nil {
+			continue
+		}
+		if at, ok := redriven[d.ID]; ok {
+			d.RedrivenAt = &at
+		}
+		if !f.Match(d) {
+			continue
+		}
+		out = append(out, d)
+		if f.Limit > 0 && len(out) >= f.Limit {
+			break
+		}
+	}
+	return out, sc.Err()
+}
+
+type redrivenMark struct {
+	ID         int64     `json:"id"`
+	RedrivenAt time.Time `json:"redriven_at"`
+}
+
+// MarkRedriven appends ids to the sidecar file.
+func (s *FileSink) MarkRedriven(_ context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path+".redriven", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	w := bufio.NewWriter(f)
+	for _, id := range ids {
+		line, err := json.Marshal(redrivenMark{ID: id, RedrivenAt: now})
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// redriven reads the sidecar file; the first mark of an ID wins.
+func (s *FileSink) redriven() (map[int64]time.Time, error) {
+	r, err := os.Open(s.path + ".redriven")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	out := make(map[int64]time.Time)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		var m redrivenMark
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			continue
+		}
+		if _, ok := out[m.ID]; !ok {
+			out[m.ID] = m.RedrivenAt
+		}
+	}
+	return out, sc.Err()
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
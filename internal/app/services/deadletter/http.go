@@ -0,0 +1,51 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewListHandler serves GET /deadletters with the ParseFilter parameters.
+func NewListHandler(src Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, err := ParseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if f.Limit == 0 {
+			f.Limit = 100
+		}
+		letters, err := src.ListDeadLetters(r.Context(), f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(letters)
+	}
+}
+
+// NewRedriveHandler serves POST /deadletters/redrive; the query selects the
+// letters to replay.
+func NewRedriveHandler(src Source, decode DecodeFunc, emit EmitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		f, err := ParseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := Redrive(r.Context(), src, f, decode, emit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
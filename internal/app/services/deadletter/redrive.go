@@ -0,0 +1,111 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// DecodeFunc turns a rejected upstream frame back into ticks, e.g.
+// websocket.Registry.Decode.
+type DecodeFunc func(feed string, frame []byte) ([]models.MarketData, error)
+
+// EmitFunc delivers re-driven ticks.
+type EmitFunc func(ctx context.Context, batch []models.MarketData) error
+
+type Result struct {
+	Redriven int      `json:"redriven"`
+	Ticks    int      `json:"ticks"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Redrive replays matching dead letters: frames rejected at decode or
+// validate are decoded again with the feed's decoder, failed store writes
+// are re-sent as-is. Letters that go through are marked re-driven.
+func Redrive(ctx context.Context, src Source, f models.DeadLetterFilter, decode DecodeFunc, emit EmitFunc) (Result, error) {
+	var res Result
+	letters, err := src.ListDeadLetters(ctx, f)
+	if err != nil {
+		return res, err
+	}
+
+	var done []int64
+	for _, d := range letters {
+		ticks, err := ticksOf(d, decode)
+		if err == nil {
+			err = emit(ctx, ticks)
+		}
+		if err != nil {
+			res.Failed++
+			res.Errors = append(res.Errors, fmt.Sprintf("%d: %v", d.ID, err))
+			continue
+		}
+		res.Redriven++
+		res.Ticks += len(ticks)
+		done = append(done, d.ID)
+	}
+	return res, src.MarkRedriven(ctx, done)
+}
+
+func ticksOf(d models.DeadLetter, decode DecodeFunc) ([]models.MarketData, error) {
+	if d.Stage != models.StageStore {
+		return decode(d.Feed, d.Raw)
+	}
+	var m models.MarketData
+	dec := json.NewDecoder(strings.NewReader(string(d.Raw)))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return []models.MarketData{m}, nil
+}
+
+// ParseFilter reads a filter from query parameters: id (comma separated),
+// feed, stage, symbol, since, until (RFC 3339), all and limit.
+func ParseFilter(q url.Values) (models.DeadLetterFilter, error) {
+	f := models.DeadLetterFilter{
+		Feed:   q.Get("feed"),
+		Stage:  q.Get("stage"),
+		Symbol: q.Get("symbol"),
+	}
+	if v := q.Get("id"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return f, fmt.Errorf("invalid id %q", s)
+			}
+			f.IDs = append(f.IDs, id)
+		}
+	}
+	for name, dst := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s %q (want RFC 3339)", name, v)
+			}
+			*dst = t
+		}
+	}
+	if v := q.Get("all"); v != "" {
+		all, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid all %q", v)
+		}
+		f.IncludeRedriven = all
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("invalid limit %q", v)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
@@ -0,0 +1,124 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sink persists dead letters, e.g. storage.Store or FileSink.
+type Sink interface {
+	PutDeadLetter(ctx context.Context, d models.DeadLetter) error
+}
+
+// Source lists dead letters for inspection and re-drive.
+type Source interface {
+	ListDeadLetters(ctx context.Context, f models.DeadLetterFilter) ([]models.DeadLetter, error)
+	MarkRedriven(ctx context.Context, ids []int64) error
+}
+
+// drainTimeout bounds how long Run keeps writing queued letters after its
+// context is done.
+const drainTimeout = 5 * time.Second
+
+// Writer hands dead letters to its sinks off the hot path. A nil *Writer
+// discards everything, so callers need no checks.
+type Writer struct {
+	ch     chan models.DeadLetter
+	sinks  []Sink
+	done   chan struct{}
+	logger *logrus.Logger
+}
+
+func NewWriter(buffer int, sinks ...Sink) *Writer {
+	return &Writer{
+		ch:     make(chan models.DeadLetter, buffer),
+		sinks:  sinks,
+		done:   make(chan struct{}),
+		logger: logger.GetLogger(),
+	}
+}
+
+// Put queues d without blocking; it is counted and dropped when the buffer
+// is full.
+func (w *Writer) Put(d models.DeadLetter) {
+	if w == nil {
+		return
+	}
+	metrics.DeadLetters.WithLabelValues(d.Feed, d.Stage).Inc()
+	select {
+	case w.ch <- d:
+	default:
+		w.logger.Error(fmt.Sprintf("Dead-letter buffer full, dropping %s message from feed %s", d.Stage, d.Feed))
+		metrics.ErrorsTotal.WithLabelValues("deadletter_dropped").Inc()
+	}
+}
+
+// Run writes queued letters until ctx is done, then drains the buffer for up
+// to drainTimeout and closes the sinks that are io.Closers. Done is closed
+// once it returns.
+func (w *Writer) Run(ctx context.Context) {
+	defer close(w.done)
+	for {
+		select {
+		case <-ctx.Done():
+			w.drain()
+			return
+		case d := <-w.ch:
+			w.write(ctx, d)
+		}
+	}
+}
+
+// Done is closed when Run has drained the buffer and closed the sinks.
+func (w *Writer) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *Writer) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	defer w.close()
+	for {
+		select {
+		case <-ctx.Done():
+			if n := len(w.ch); n > 0 {
+				w.logger.Error(fmt.Sprintf("Dead-letter drain timed out, dropping %d letters", n))
+				metrics.ErrorsTotal.WithLabelValues("deadletter_dropped").Add(float64(n))
+			}
+			return
+		case d := <-w.ch:
+			w.write(ctx, d)
+		default:
+			return
+		}
+	}
+}
+
+func (w *Writer) write(ctx context.Context, d models.DeadLetter) {
+	for _, s := range w.sinks {
+		if err := s.PutDeadLetter(ctx, d); err != nil {
+			w.logger.Error(fmt.Sprintf("Failed to write dead letter: %v", err))
+			metrics.ErrorsTotal.WithLabelValues("deadletter_write").Inc()
+		}
+	}
+}
+
+// close closes the sinks the writer owns; shared ones such as the store
+// have no io.Closer and are closed by their owner.
+func (w *Writer) close() {
+	for _, s := range w.sinks {
+		if c, ok := s.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				w.logger.Error(fmt.Sprintf("Failed to close dead-letter sink: %v", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package decoder
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	common "ws_ingestor/internal/app/common/exception_handler"
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/models"
+)
+
+// Decoder turns one upstream frame into zero or more MarketData records.
+// messageType is the websocket frame type (text or binary).
+type Decoder interface {
+	Decode(messageType int, frame []byte) ([]models.MarketData, error)
+}
+
+// Factory builds a Decoder for a feed.
+type Factory func(feed config.FeedConfig) (Decoder, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"json": func(config.FeedConfig) (Decoder, error) { return JSON{}, nil },
+		"batch": func(config.FeedConfig) (Decoder, error) {
+			return Batch{}, nil
+		},
+		"mapping": func(feed config.FeedConfig) (Decoder, error) {
+			if feed.Mapping == nil {
+				return nil, fmt.Errorf("parser mapping requires a mapping section")
+			}
+			return NewMapping(*feed.Mapping)
+		},
+	}
+)
+
+// Register makes a decoder available under name for use as a feed parser.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = f
+}
+
+// New returns the decoder selected by feed.Parser.
+func New(feed config.FeedConfig) (Decoder, error) {
+	mu.RLock()
+	f, ok := factories[feed.Parser]
+	mu.RUnlock()
+	if !ok {
+		return nil, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Unknown parser %q for feed %s (available: %v)", feed.Parser, feed.Name, Names()), nil)
+	}
+	d, err := f(feed)
+	if err != nil {
+		return nil, common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Failed to build parser %q for feed %s", feed.Parser, feed.Name), err)
+	}
+	return d, nil
+}
+
+// Names lists the registered parser names.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for n := range factories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// JSON decodes the native format: one MarketData object per frame.
+type JSON struct{}
+
+func (JSON) Decode(_ int, frame []byte) ([]models.MarketData, error) {
+	var data models.MarketData
+	if err := unmarshal(frame, &data); err != nil {
+		return nil, err
+	}
+	return []models.MarketData{data}, nil
+}
+
+// Batch decodes a JSON array of MarketData objects. A bare object is accepted
+// as a batch of one.
+type Batch struct{}
+
+func (Batch) Decode(mt int, frame []byte) ([]models.MarketData, error) {
+	trimmed := bytes.TrimLeft(frame, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return JSON{}.Decode(mt, frame)
+	}
+	var batch []models.MarketData
+	if err := unmarshal(trimmed, &batch); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// unmarshal decodes numbers as json.Number so prices keep their exact
+// decimal text until they are converted to models.Decimal.
+func unmarshal(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
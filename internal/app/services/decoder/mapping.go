@@ -0,0 +1,207 @@
+package decoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/models"
+)
+
+// Mapping decodes arbitrary JSON envelopes using configured field paths.
+type Mapping struct {
+	root      path
+	match     []matcher
+	name      path
+	timestamp path
+	tsUnit    time.Duration
+	exchange  path
+	seq       path
+	data      path
+	fields    []fieldPath
+	aliases   []config.KeyAlias
+}
+
+type matcher struct {
+	path  path
+	value string
+}
+
+type fieldPath struct {
+	field string
+	path  path
+}
+
+func NewMapping(cfg config.MappingConfig) (*Mapping, error) {
+	if cfg.Name == "" || cfg.Timestamp == "" {
+		return nil, fmt.Errorf("mapping requires name and timestamp paths")
+	}
+
+	m := &Mapping{aliases: cfg.Aliases}
+	var err error
+	compile := func(expr string) path {
+		if err != nil {
+			return nil
+		}
+		var p path
+		p, err = compilePath(expr)
+		return p
+	}
+
+	m.root = compile(cfg.Root)
+	m.name = compile(cfg.Name)
+	m.timestamp = compile(cfg.Timestamp)
+	if cfg.Exchange != "" {
+		m.exchange = compile(cfg.Exchange)
+	}
+	if cfg.Seq != "" {
+		m.seq = compile(cfg.Seq)
+	}
+	m.data = compile(cfg.Data)
+	for _, r := range cfg.Match {
+		m.match = append(m.match, matcher{path: compile(r.Path), value: r.Value})
+	}
+	for _, f := range cfg.Fields {
+		m.fields = append(m.fields, fieldPath{field: f.Field, path: compile(f.Path)})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.TimestampUnit {
+	case "s":
+		m.tsUnit = time.Second
+	case "", "ms":
+		m.tsUnit = time.Millisecond
+	case "us":
+		m.tsUnit = time.Microsecond
+	case "ns":
+		m.tsUnit = time.Nanosecond
+	default:
+		return nil, fmt.Errorf("unknown timestamp_unit %q", cfg.TimestampUnit)
+	}
+	return m, nil
+}
+
+func (m *Mapping) Decode(_ int, frame []byte) ([]models.MarketData, error) {
+	var doc any
+	if err := unmarshal(frame, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, mt := range m.match {
+		v, ok := mt.path.lookup(doc)
+		if !ok || fmt.Sprint(v) != mt.value {
+			return nil, nil
+		}
+	}
+
+	root, ok := m.root.lookup(doc)
+	if !ok {
+		return nil, fmt.Errorf("root path not found")
+	}
+	records, isArr := root.([]any)
+	if !isArr {
+		records = []any{root}
+	}
+
+	out := make([]models.MarketData, 0, len(records))
+	for _, rec := range records {
+		d, err := m.record(rec)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (m *Mapping) record(rec any) (models.MarketData, error) {
+	var d models.MarketData
+
+	name, ok := m.name.lookup(rec)
+	if !ok {
+		return d, fmt.Errorf("name path not found")
+	}
+	d.Name = fmt.Sprint(name)
+
+	ts, ok := m.timestamp.lookup(rec)
+	if !ok {
+		return d, fmt.Errorf("timestamp path not found")
+	}
+	millis, err := m.toMillis(ts)
+	if err != nil {
+		return d, err
+	}
+	d.Timestamp = millis
+
+	if m.exchange != nil {
+		if ex, ok := m.exchange.lookup(rec); ok {
+			d.Exchange = fmt.Sprint(ex)
+		}
+	}
+
+	if m.seq != nil {
+		if v, ok := m.seq.lookup(rec); ok {
+			if n, isNum := v.(json.Number); isNum {
+				d.Seq, _ = n.Int64()
+			}
+		}
+	}
+
+	// Keep the {"data": {...}} envelope the rest of the pipeline expects
+	inner := map[string]any{}
+	if len(m.fields) > 0 {
+		for _, f := range m.fields {
+			if v, ok := f.path.lookup(rec); ok {
+				inner[f.field] = v
+			}
+		}
+	} else if v, ok := m.data.lookup(rec); ok {
+		if obj, isObj := v.(map[string]any); isObj {
+			inner = obj
+		} else {
+			inner["value"] = v
+		}
+	}
+	for _, a := range m.aliases {
+		if v, ok := inner[a.From]; ok {
+			if _, taken := inner[a.To]; !taken {
+				inner[a.To] = v
+				delete(inner, a.From)
+			}
+		}
+	}
+	d.Data = map[string]any{"data": inner}
+	return d, nil
+}
+
+func (m *Mapping) toMillis(v any) (int64, error) {
+	var n float64
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil && m.tsUnit == time.Millisecond {
+			return i, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("unparseable timestamp %q", t)
+		}
+		n = f
+	case string:
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			n = f
+			break
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return 0, fmt.Errorf("unparseable timestamp %q", t)
+		}
+		return parsed.UnixMilli(), nil
+	default:
+		return 0, fmt.Errorf("unsupported timestamp type %T", v)
+	}
+	return int64(n * float64(m.tsUnit) / float64(time.Millisecond)), nil
+}
@@ -0,0 +1,92 @@
+package decoder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// path is a compiled JSONPath-style expression limited to member access and
+// array indexes: $, $.a.b, $.a[0].b, $['a-b'].
+type path []step
+
+type step struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func compilePath(expr string) (path, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" || expr == "$" {
+		return path{}, nil
+	}
+	if !strings.HasPrefix(expr, "$") {
+		expr = "$." + expr
+	}
+
+	var p path
+	rest := expr[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty member in path %q", expr)
+			}
+			p = append(p, step{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in path %q", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p = append(p, step{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in path %q", inner, expr)
+			}
+			p = append(p, step{index: idx, isIdx: true})
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", rest[0], expr)
+		}
+	}
+	return p, nil
+}
+
+func (p path) lookup(v any) (any, bool) {
+	for _, s := range p {
+		if s.isIdx {
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			i := s.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, false
+			}
+			v = arr[i]
+			continue
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[s.key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,99 @@
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+)
+
+// Acker is told which logged ticks were dropped, e.g. wal.Log.
+type Acker interface {
+	Ack(offsets ...int64)
+}
+
+type key struct {
+	name    string
+	ts      int64
+	payload uint64
+}
+
+// Deduper drops records already seen within a bounded window of the most
+// recent distinct (name, timestamp, payload hash) keys. Feed and exchange are
+// not part of the key, so the same tick from redundant feeds is dropped too.
+type Deduper struct {
+	acker Acker
+
+	mu   sync.Mutex
+	seen map[key]struct{}
+	ring []key
+	next int
+	full bool
+}
+
+// New builds a deduper over the last window keys. acker is optional; when
+// set, dropped ticks that came from the WAL are acknowledged so the
+// checkpoint can move past them.
+func New(window int, acker Acker) *Deduper {
+	return &Deduper{
+		acker: acker,
+		seen:  make(map[key]struct{}, window),
+		ring:  make([]key, window),
+	}
+}
+
+// Seen reports whether m is a duplicate; if not, m is remembered.
+func (d *Deduper) Seen(m models.MarketData) bool {
+	k := keyOf(m)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, dup := d.seen[k]; dup {
+		return true
+	}
+	if d.full {
+		delete(d.seen, d.ring[d.next])
+	}
+	d.ring[d.next] = k
+	d.seen[k] = struct{}{}
+	d.next++
+	if d.next == len(d.ring) {
+		d.next = 0
+		d.full = true
+	}
+	return false
+}
+
+// Run forwards unique records from in to out until ctx is done.
+func (d *Deduper) Run(ctx context.Context, in <-chan models.MarketData, out chan<- models.MarketData) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-in:
+			if d.Seen(m) {
+				metrics.DuplicatesDropped.WithLabelValues("memory").Inc()
+				if d.acker != nil && m.Offset > 0 {
+					d.acker.Ack(m.Offset)
+				}
+				continue
+			}
+			select {
+			case out <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func keyOf(m models.MarketData) key {
+	h := fnv.New64a()
+	// encoding/json sorts map keys, so equal payloads hash equally
+	payload, _ := json.Marshal(m.Data)
+	h.Write(payload)
+	return key{name: m.Name, ts: m.Timestamp, payload: h.Sum64()}
+}
@@ -0,0 +1,226 @@
+// Package export streams stored ticks and bars out of Postgres as CSV,
+// newline-delimited JSON or Parquet. Rows are written as they are read, so
+// memory use does not grow with the size of the range.
+package export
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/bars"
+	"ws_ingestor/internal/app/services/instruments"
+	"ws_ingestor/internal/app/services/storage"
+	"ws_ingestor/internal/app/services/transform"
+	"ws_ingestor/internal/utils"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Kind selects what is exported.
+type Kind string
+
+const (
+	Ticks Kind = "ticks"
+	Bars  Kind = "bars"
+)
+
+// Format is the output encoding.
+type Format string
+
+const (
+	CSV     Format = "csv"
+	NDJSON  Format = "ndjson"
+	Parquet Format = "parquet"
+)
+
+// Compression wraps CSV and NDJSON output; Parquet uses it as the column
+// codec instead, so the file stays readable by Parquet tools.
+type Compression string
+
+const (
+	None Compression = "none"
+	Gzip Compression = "gzip"
+	Zstd Compression = "zstd"
+)
+
+// Request describes one export.
+type Request struct {
+	Kind        Kind
+	Symbols     []string
+	Interval    string // bars only
+	From        int64  // Unix ms, inclusive; zero for no bound
+	To          int64  // Unix ms, exclusive; zero for no bound
+	Format      Format
+	Compression Compression
+}
+
+// ParseRequest reads kind, symbols, interval, from, to, format and compress
+// parameters, the same for the CLI and HTTP.
+func ParseRequest(q url.Values) (Request, error) {
+	r := Request{
+		Kind:        Kind(q.Get("kind")),
+		Interval:    q.Get("interval"),
+		Format:      Format(q.Get("format")),
+		Compression: Compression(q.Get("compress")),
+	}
+	for _, s := range strings.Split(q.Get("symbols"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			r.Symbols = append(r.Symbols, s)
+		}
+	}
+	if len(r.Symbols) == 0 {
+		return r, fmt.Errorf("symbols is required")
+	}
+	var err error
+	if r.From, err = utils.ParseMillis(q.Get("from")); err != nil {
+		return r, fmt.Errorf("invalid from %q", q.Get("from"))
+	}
+	if r.To, err = utils.ParseMillis(q.Get("to")); err != nil {
+		return r, fmt.Errorf("invalid to %q", q.Get("to"))
+	}
+
+	switch r.Kind {
+	case "":
+		r.Kind = Ticks
+	case Ticks:
+	case Bars:
+		if _, ok := bars.Lookup(r.Interval); !ok {
+			return r, fmt.Errorf("invalid interval %q", r.Interval)
+		}
+	default:
+		return r, fmt.Errorf("invalid kind %q (want ticks or bars)", r.Kind)
+	}
+	switch r.Format {
+	case "":
+		r.Format = CSV
+	case CSV, NDJSON, Parquet:
+	default:
+		return r, fmt.Errorf("invalid format %q (want csv, ndjson or parquet)", r.Format)
+	}
+	switch r.Compression {
+	case "":
+		r.Compression = None
+	case None, Gzip, Zstd:
+	default:
+		return r, fmt.Errorf("invalid compress %q (want none, gzip or zstd)", r.Compression)
+	}
+	return r, nil
+}
+
+// Filename is a suggested name for the output, e.g. ticks.csv.gz.
+func (r Request) Filename() string {
+	name := string(r.Kind) + "." + string(r.Format)
+	if r.Format != Parquet {
+		switch r.Compression {
+		case Gzip:
+			name += ".gz"
+		case Zstd:
+			name += ".zst"
+		}
+	}
+	return name
+}
+
+// ContentType is the media type of the output.
+func (r Request) ContentType() string {
+	if r.Format != Parquet {
+		switch r.Compression {
+		case Gzip:
+			return "application/gzip"
+		case Zstd:
+			return "application/zstd"
+		}
+	}
+	switch r.Format {
+	case NDJSON:
+		return "application/x-ndjson"
+	case Parquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+type Exporter struct {
+	store   *storage.Store
+	catalog *instruments.Catalog
+}
+
+// New builds an exporter; catalog supplies instrument precision for
+// transform profiles.
+func New(store *storage.Store, catalog *instruments.Catalog) *Exporter {
+	return &Exporter{store: store, catalog: catalog}
+}
+
+// Run writes the rows selected by req to w and returns how many were
+// written. profile (optional) is a client's transform configuration, applied
+// as for its /ws frames.
+func (e *Exporter) Run(ctx context.Context, req Request, profile *dto.ClientConfig, w io.Writer) (int64, error) {
+	var (
+		out io.Writer = w
+		zc  io.WriteCloser
+	)
+	if req.Format != Parquet {
+		switch req.Compression {
+		case Gzip:
+			zc = gzip.NewWriter(w)
+		case Zstd:
+			enc, err := zstd.NewWriter(w)
+			if err != nil {
+				return 0, err
+			}
+			zc = enc
+		}
+		if zc != nil {
+			out = zc
+		}
+	}
+
+	columns := tickColumns
+	if req.Kind == Bars {
+		columns = barColumns
+	}
+	sink, err := newSink(req, columns, out)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	emit := func(flat dto.FlatMarketData, symbol, exchange string) error {
+		rec := record{flat: flat}
+		if profile != nil && profile.Symbols != nil {
+			if sc, ok := profile.Symbols[symbol]; ok {
+				rec.flat = transform.Apply(flat, &sc, e.catalog.Spec(symbol, exchange))
+				rec.renamed = sc.RenameFields
+			}
+		}
+		n++
+		return sink.write(rec)
+	}
+	rng := storage.Range{Symbols: req.Symbols, From: req.From, To: req.To}
+	if req.Kind == Bars {
+		rng.Interval = req.Interval
+		err = e.store.StreamCandles(ctx, rng, func(c models.Candle) error {
+			return emit(transform.Bar(&c), c.Symbol, c.Exchange)
+		})
+	} else {
+		err = e.store.StreamTicks(ctx, rng, func(m models.MarketData) error {
+			return emit(transform.Tick(m), m.Name, m.Exchange)
+		})
+	}
+	if err != nil {
+		return n, err
+	}
+	if err := sink.close(); err != nil {
+		return n, err
+	}
+	if zc != nil {
+		return n, zc.Close()
+	}
+	return n, nil
+}
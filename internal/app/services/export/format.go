@@ -0,0 +1,251 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+type columnType int
+
+const (
+	stringColumn columnType = iota
+	intColumn
+	decimalColumn
+)
+
+// column is one field of the fixed CSV and Parquet layouts.
+type column struct {
+	name string
+	typ  columnType
+}
+
+var tickColumns = []column{
+	{"symbol", stringColumn},
+	{"timestamp", intColumn},
+	{"exchange", stringColumn},
+	{"feed", stringColumn},
+	{"kind", stringColumn},
+	{"price", decimalColumn},
+	{"qty", decimalColumn},
+	{"side", stringColumn},
+	{"bid", decimalColumn},
+	{"ask", decimalColumn},
+	{"bid_size", decimalColumn},
+	{"ask_size", decimalColumn},
+	{"open", decimalColumn},
+	{"high", decimalColumn},
+	{"low", decimalColumn},
+	{"close", decimalColumn},
+	{"volume", decimalColumn},
+	{"interval", stringColumn},
+}
+
+var barColumns = []column{
+	{"symbol", stringColumn},
+	{"exchange", stringColumn},
+	{"interval", stringColumn},
+	{"start", intColumn},
+	{"end", intColumn},
+	{"open", decimalColumn},
+	{"high", decimalColumn},
+	{"low", decimalColumn},
+	{"close", decimalColumn},
+	{"volume", decimalColumn},
+	{"ticks", intColumn},
+}
+
+// record is one flattened row. renamed maps original field names to the
+// names a transform profile gave them, so the fixed layouts can find them.
+type record struct {
+	flat    dto.FlatMarketData
+	renamed map[string]string
+}
+
+func (r record) get(name string) any {
+	if to, ok := r.renamed[name]; ok {
+		name = to
+	}
+	return r.flat[name]
+}
+
+type sink interface {
+	write(rec record) error
+	close() error
+}
+
+func newSink(req Request, columns []column, w io.Writer) (sink, error) {
+	switch req.Format {
+	case NDJSON:
+		return &ndjsonSink{enc: json.NewEncoder(w)}, nil
+	case Parquet:
+		return newParquetSink(req.Compression, columns, w), nil
+	}
+	return newCSVSink(columns, w)
+}
+
+// ndjsonSink writes every field of the flattened record, renames included.
+type ndjsonSink struct {
+	enc *json.Encoder
+}
+
+func (s *ndjsonSink) write(rec record) error { return s.enc.Encode(rec.flat) }
+func (s *ndjsonSink) close() error           { return nil }
+
+// csvSink writes the fixed columns; decimals keep their exact text.
+type csvSink struct {
+	w       *csv.Writer
+	columns []column
+	row     []string
+}
+
+func newCSVSink(columns []column, w io.Writer) (*csvSink, error) {
+	s := &csvSink{w: csv.NewWriter(w), columns: columns, row: make([]string, len(columns))}
+	for i, c := range columns {
+		s.row[i] = c.name
+	}
+	return s, s.w.Write(s.row)
+}
+
+func (s *csvSink) write(rec record) error {
+	for i, c := range s.columns {
+		s.row[i] = text(rec.get(c.name))
+	}
+	return s.w.Write(s.row)
+}
+
+func (s *csvSink) close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func text(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case fmt.Stringer: // models.Decimal, json.Number
+		return x.String()
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case int:
+		return strconv.Itoa(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	// String types such as models.TickKind; anything else as JSON
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String()
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// parquetRowGroup bounds how many rows are buffered before a row group is
+// written out.
+const parquetRowGroup = 64 * 1024
+
+// parquetSink writes the fixed columns, all optional; decimals are stored as
+// doubles for analysis tools.
+type parquetSink struct {
+	w       *parquet.GenericWriter[map[string]any]
+	columns []column
+	buf     []map[string]any
+}
+
+func newParquetSink(c Compression, columns []column, w io.Writer) *parquetSink {
+	group := parquet.Group{}
+	for _, col := range columns {
+		var node parquet.Node
+		switch col.typ {
+		case intColumn:
+			node = parquet.Int(64)
+		case decimalColumn:
+			node = parquet.Leaf(parquet.DoubleType)
+		default:
+			node = parquet.String()
+		}
+		group[col.name] = parquet.Optional(node)
+	}
+	var codec compress.Codec = &parquet.Uncompressed
+	switch c {
+	case Gzip:
+		codec = &parquet.Gzip
+	case Zstd:
+		codec = &parquet.Zstd
+	}
+	return &parquetSink{
+		w:       parquet.NewGenericWriter[map[string]any](w, parquet.NewSchema("market_data", group), parquet.Compression(codec), parquet.MaxRowsPerRowGroup(parquetRowGroup)),
+		columns: columns,
+		buf:     make([]map[string]any, 0, 1024),
+	}
+}
+
+func (s *parquetSink) write(rec record) error {
+	row := make(map[string]any, len(s.columns))
+	for _, c := range s.columns {
+		v := rec.get(c.name)
+		if v == nil {
+			continue
+		}
+		switch c.typ {
+		case intColumn:
+			if n, ok := integer(v); ok {
+				row[c.name] = n
+			}
+		case decimalColumn:
+			if d, ok := models.DecimalFrom(v); ok {
+				row[c.name] = d.Float64()
+			}
+		default:
+			row[c.name] = text(v)
+		}
+	}
+	s.buf = append(s.buf, row)
+	if len(s.buf) == cap(s.buf) {
+		return s.flush()
+	}
+	return nil
+}
+
+func integer(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case int:
+		return int64(x), true
+	case json.Number:
+		n, err := x.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(x, 10, 64)
+		return n, err == nil
+	}
+	d, ok := models.DecimalFrom(v)
+	return int64(d.Float64()), ok
+}
+
+func (s *parquetSink) flush() error {
+	_, err := s.w.Write(s.buf)
+	s.buf = s.buf[:0]
+	return err
+}
+
+func (s *parquetSink) close() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.w.Close()
+}
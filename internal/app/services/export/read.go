@@ -0,0 +1,172 @@
+package export
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ws_ingestor/internal/app/models"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Reader reads ticks back from a file written by a ticks export. The format
+// and compression follow the file name, e.g. ticks.ndjson.zst; typed views
+// are rebuilt from the flat columns with Classify.
+type Reader struct {
+	next    func() (map[string]any, error)
+	closers []io.Closer
+}
+
+// Open opens an exported ticks file.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{closers: []io.Closer{f}}
+	var in io.Reader = f
+
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		in, r.closers = zr, append(r.closers, zr)
+		name = strings.TrimSuffix(name, ".gz")
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		in, r.closers = zr, append(r.closers, zr.IOReadCloser())
+		name = strings.TrimSuffix(name, ".zst")
+	}
+
+	switch filepath.Ext(name) {
+	case ".ndjson", ".jsonl", ".json":
+		dec := json.NewDecoder(in)
+		dec.UseNumber()
+		r.next = func() (map[string]any, error) {
+			var rec map[string]any
+			err := dec.Decode(&rec)
+			return rec, err
+		}
+	case ".csv":
+		cr := csv.NewReader(in)
+		cr.ReuseRecord = true
+		header, err := cr.Read()
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("read header: %w", err)
+		}
+		header = append([]string(nil), header...)
+		r.next = func() (map[string]any, error) {
+			row, err := cr.Read()
+			if err != nil {
+				return nil, err
+			}
+			rec := make(map[string]any, len(header))
+			for i, v := range row {
+				if v != "" && i < len(header) {
+					rec[header[i]] = v
+				}
+			}
+			return rec, nil
+		}
+	case ".parquet":
+		if in != io.Reader(f) {
+			r.Close()
+			return nil, fmt.Errorf("%s: parquet files carry their own compression", path)
+		}
+		st, err := f.Stat()
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		pf, err := parquet.OpenFile(f, st.Size())
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		pr := parquet.NewGenericReader[map[string]any](f, pf.Schema())
+		r.closers = append(r.closers, pr)
+		buf := make([]map[string]any, 1)
+		r.next = func() (map[string]any, error) {
+			buf[0] = make(map[string]any)
+			n, err := pr.Read(buf)
+			if n == 1 {
+				return buf[0], nil
+			}
+			if err == nil {
+				err = io.EOF
+			}
+			return nil, err
+		}
+	default:
+		r.Close()
+		return nil, fmt.Errorf("%s: unknown export format", path)
+	}
+	return r, nil
+}
+
+// Next returns the next tick, or io.EOF after the last one.
+func (r *Reader) Next() (models.MarketData, error) {
+	rec, err := r.next()
+	if err != nil {
+		return models.MarketData{}, err
+	}
+	return tickFrom(rec)
+}
+
+// Close releases the file.
+func (r *Reader) Close() error {
+	var err error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if cerr := r.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// tickFrom rebuilds a tick from a flat record: the identifying fields go to
+// MarketData and everything else into the payload.
+func tickFrom(rec map[string]any) (models.MarketData, error) {
+	var m models.MarketData
+	symbol, _ := rec["symbol"].(string)
+	ts, ok := integer(rec["timestamp"])
+	if symbol == "" || !ok {
+		return m, fmt.Errorf("record without symbol and timestamp; not a ticks export")
+	}
+	m.Name, m.Timestamp = symbol, ts
+	m.Exchange, _ = rec["exchange"].(string)
+	m.Feed, _ = rec["feed"].(string)
+	if kind, _ := rec["kind"].(string); kind != "" {
+		m.Kind = models.TickKind(kind)
+	}
+
+	payload := make(map[string]any, len(rec))
+	for k, v := range rec {
+		switch k {
+		case "symbol", "timestamp", "exchange", "feed", "kind":
+		default:
+			if v != nil {
+				payload[k] = v
+			}
+		}
+	}
+	m.Data = map[string]any{"data": payload}
+	m.Classify()
+	return m, nil
+}
@@ -0,0 +1,64 @@
+package gaps
+
+import (
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+type mark struct {
+	ts  int64
+	seq int64
+}
+
+// Detector tracks the last timestamp and sequence number seen per symbol and
+// reports gaps between consecutive ticks.
+type Detector struct {
+	feed      string
+	threshold time.Duration
+
+	mu   sync.Mutex
+	last map[string]mark
+}
+
+// NewDetector flags timestamp gaps wider than threshold (0 disables them);
+// sequence gaps are always flagged when the feed provides sequence numbers.
+func NewDetector(feed string, threshold time.Duration) *Detector {
+	return &Detector{feed: feed, threshold: threshold, last: make(map[string]mark)}
+}
+
+// Observe records the tick and returns the gap preceding it, if any.
+// Out-of-order ticks are ignored.
+func (d *Detector) Observe(m models.MarketData) (models.DataGap, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, seen := d.last[m.Name]
+	if seen && m.Timestamp < prev.ts {
+		return models.DataGap{}, false
+	}
+	d.last[m.Name] = mark{ts: m.Timestamp, seq: m.Seq}
+	if !seen {
+		return models.DataGap{}, false
+	}
+
+	gap := models.DataGap{
+		Feed:       d.feed,
+		Symbol:     m.Name,
+		From:       prev.ts,
+		To:         m.Timestamp,
+		DetectedAt: time.Now(),
+	}
+	if prev.seq > 0 && m.Seq > prev.seq+1 {
+		gap.Kind = "sequence"
+		gap.FromSeq = prev.seq
+		gap.ToSeq = m.Seq
+		return gap, true
+	}
+	if d.threshold > 0 && time.Duration(m.Timestamp-prev.ts)*time.Millisecond > d.threshold {
+		gap.Kind = "timestamp"
+		return gap, true
+	}
+	return models.DataGap{}, false
+}
@@ -0,0 +1,92 @@
+package gaps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/decoder"
+
+	"github.com/gorilla/websocket"
+)
+
+// GapFiller backfills the ticks missing from a gap, typically from a vendor
+// REST snapshot endpoint. Returned records may include the boundary ticks;
+// callers drop anything outside the open interval (From, To).
+type GapFiller interface {
+	FillGap(ctx context.Context, gap models.DataGap) ([]models.MarketData, error)
+}
+
+// RESTFiller requests GET <url>?symbol=&from=&to=[&from_seq=&to_seq=] and
+// decodes the body with the configured decoder.
+type RESTFiller struct {
+	url        string
+	authHeader string
+	apiKey     string
+	decoder    decoder.Decoder
+	client     *http.Client
+}
+
+func NewRESTFiller(endpoint, authHeader, apiKey string, dec decoder.Decoder) *RESTFiller {
+	return &RESTFiller{
+		url:        endpoint,
+		authHeader: authHeader,
+		apiKey:     apiKey,
+		decoder:    dec,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (f *RESTFiller) FillGap(ctx context.Context, gap models.DataGap) ([]models.MarketData, error) {
+	u, err := url.Parse(f.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("symbol", gap.Symbol)
+	q.Set("from", strconv.FormatInt(gap.From, 10))
+	q.Set("to", strconv.FormatInt(gap.To, 10))
+	if gap.Kind == "sequence" {
+		q.Set("from_seq", strconv.FormatInt(gap.FromSeq, 10))
+		q.Set("to_seq", strconv.FormatInt(gap.ToSeq, 10))
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.authHeader != "" && f.apiKey != "" {
+		req.Header.Set(f.authHeader, f.apiKey)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gap fill %s returned %s", gap.Symbol, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return f.decoder.Decode(websocket.TextMessage, body)
+}
+
+// Within reports whether m falls strictly inside the gap.
+func Within(gap models.DataGap, m models.MarketData) bool {
+	if m.Name != gap.Symbol {
+		return false
+	}
+	if gap.Kind == "sequence" && m.Seq > 0 {
+		return m.Seq > gap.FromSeq && m.Seq < gap.ToSeq
+	}
+	return m.Timestamp > gap.From && m.Timestamp < gap.To
+}
@@ -0,0 +1,123 @@
+package gaps
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/decoder"
+)
+
+func TestRESTFillerTimestampGap(t *testing.T) {
+	var got url.Values
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		auth = r.Header.Get("X-Api-Key")
+		w.Write([]byte(`[
+			{"name":"NIFTY","timestamp":1001,"exchange":"nse","data":{"ltp":101.5}},
+			{"name":"NIFTY","timestamp":1002,"exchange":"nse","data":{"ltp":101.75}}
+		]`))
+	}))
+	defer srv.Close()
+
+	f := NewRESTFiller(srv.URL+"/snapshot?venue=nse", "X-Api-Key", "secret", decoder.Batch{})
+	records, err := f.FillGap(context.Background(), models.DataGap{
+		Symbol: "NIFTY",
+		Kind:   "timestamp",
+		From:   1000,
+		To:     1003,
+	})
+	if err != nil {
+		t.Fatalf("FillGap: %v", err)
+	}
+
+	want := map[string]string{"venue": "nse", "symbol": "NIFTY", "from": "1000", "to": "1003"}
+	for k, v := range want {
+		if got.Get(k) != v {
+			t.Errorf("query %s = %q, want %q", k, got.Get(k), v)
+		}
+	}
+	for _, k := range []string{"from_seq", "to_seq"} {
+		if got.Has(k) {
+			t.Errorf("query has %s for a timestamp gap", k)
+		}
+	}
+	if auth != "secret" {
+		t.Errorf("auth header = %q, want %q", auth, "secret")
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("decoded %d records, want 2", len(records))
+	}
+	if records[0].Name != "NIFTY" || records[0].Timestamp != 1001 || records[1].Timestamp != 1002 {
+		t.Errorf("decoded %+v", records)
+	}
+}
+
+func TestRESTFillerSequenceGap(t *testing.T) {
+	var got url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		w.Write([]byte(`{"name":"NIFTY","timestamp":1001,"seq":8,"data":{}}`))
+	}))
+	defer srv.Close()
+
+	f := NewRESTFiller(srv.URL, "", "", decoder.Batch{})
+	records, err := f.FillGap(context.Background(), models.DataGap{
+		Symbol:  "NIFTY",
+		Kind:    "sequence",
+		From:    1000,
+		To:      1003,
+		FromSeq: 7,
+		ToSeq:   10,
+	})
+	if err != nil {
+		t.Fatalf("FillGap: %v", err)
+	}
+	if got.Get("from_seq") != "7" || got.Get("to_seq") != "10" {
+		t.Errorf("sequence query = %v", got)
+	}
+	if len(records) != 1 || records[0].Seq != 8 {
+		t.Errorf("decoded %+v", records)
+	}
+}
+
+func TestRESTFillerStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	f := NewRESTFiller(srv.URL, "", "", decoder.Batch{})
+	if _, err := f.FillGap(context.Background(), models.DataGap{Symbol: "NIFTY"}); err == nil {
+		t.Fatal("FillGap succeeded on a 429 response")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	ts := models.DataGap{Symbol: "NIFTY", Kind: "timestamp", From: 1000, To: 1003}
+	seq := models.DataGap{Symbol: "NIFTY", Kind: "sequence", From: 1000, To: 1003, FromSeq: 7, ToSeq: 10}
+	cases := []struct {
+		name string
+		gap  models.DataGap
+		m    models.MarketData
+		want bool
+	}{
+		{"inside", ts, models.MarketData{Name: "NIFTY", Timestamp: 1001}, true},
+		{"lower bound", ts, models.MarketData{Name: "NIFTY", Timestamp: 1000}, false},
+		{"upper bound", ts, models.MarketData{Name: "NIFTY", Timestamp: 1003}, false},
+		{"other symbol", ts, models.MarketData{Name: "BANKNIFTY", Timestamp: 1001}, false},
+		{"sequence inside", seq, models.MarketData{Name: "NIFTY", Timestamp: 5000, Seq: 9}, true},
+		{"sequence bound", seq, models.MarketData{Name: "NIFTY", Timestamp: 1001, Seq: 10}, false},
+		{"sequence without seq", seq, models.MarketData{Name: "NIFTY", Timestamp: 1001}, true},
+	}
+	for _, c := range cases {
+		if got := Within(c.gap, c.m); got != c.want {
+			t.Errorf("%s: Within = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
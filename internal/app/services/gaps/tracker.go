@@ -0,0 +1,105 @@
+package gaps
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Recorder persists detected gaps.
+type Recorder interface {
+	RecordGap(ctx context.Context, gap models.DataGap) (int64, error)
+	MarkGapFilled(ctx context.Context, id int64, records int) error
+}
+
+// Tracker runs gap detection for one feed, records every gap and, when the
+// feed has a GapFiller, pushes the backfilled ticks into out.
+type Tracker struct {
+	detector *Detector
+	filler   GapFiller
+	recorder Recorder
+	out      chan<- models.MarketData
+	enrich   func(*models.MarketData)
+	logger   *logrus.Logger
+}
+
+// NewTracker builds a tracker; filler and recorder may be nil. enrich is
+// applied to backfilled records before they are emitted.
+func NewTracker(feed string, threshold time.Duration, filler GapFiller, recorder Recorder, out chan<- models.MarketData, enrich func(*models.MarketData)) *Tracker {
+	return &Tracker{
+		detector: NewDetector(feed, threshold),
+		filler:   filler,
+		recorder: recorder,
+		out:      out,
+		enrich:   enrich,
+		logger:   logger.GetLogger(),
+	}
+}
+
+// Observe feeds one live tick to the detector.
+func (t *Tracker) Observe(ctx context.Context, m models.MarketData) {
+	gap, ok := t.detector.Observe(m)
+	if !ok {
+		return
+	}
+	metrics.GapsDetected.WithLabelValues(gap.Feed, gap.Kind).Inc()
+	t.logger.Warn(fmt.Sprintf("Gap detected on feed %s for %s (%s): %d -> %d", gap.Feed, gap.Symbol, gap.Kind, gap.From, gap.To))
+	go t.handle(ctx, gap)
+}
+
+func (t *Tracker) handle(ctx context.Context, gap models.DataGap) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.logger.WithField("panic", r).Error("Gap handler panicked")
+		}
+	}()
+
+	var id int64
+	if t.recorder != nil {
+		var err error
+		if id, err = t.recorder.RecordGap(ctx, gap); err != nil {
+			t.logger.Error(fmt.Sprintf("Failed to record gap for %s: %v", gap.Symbol, err))
+			metrics.ErrorsTotal.WithLabelValues("gap_record").Inc()
+		}
+	}
+	if t.filler == nil {
+		return
+	}
+
+	records, err := t.filler.FillGap(ctx, gap)
+	if err != nil {
+		t.logger.Error(fmt.Sprintf("Gap fill failed for %s on feed %s: %v", gap.Symbol, gap.Feed, err))
+		metrics.ErrorsTotal.WithLabelValues("gap_fill").Inc()
+		return
+	}
+
+	filled := 0
+	for _, m := range records {
+		if !Within(gap, m) || m.Validate() != nil {
+			continue
+		}
+		if t.enrich != nil {
+			t.enrich(&m)
+		}
+		select {
+		case t.out <- m:
+			filled++
+		case <-ctx.Done():
+			return
+		}
+	}
+	metrics.GapRecordsFilled.WithLabelValues(gap.Feed).Add(float64(filled))
+	t.logger.Info(fmt.Sprintf("Backfilled %d records for %s on feed %s", filled, gap.Symbol, gap.Feed))
+
+	if t.recorder != nil && id > 0 {
+		if err := t.recorder.MarkGapFilled(ctx, id, filled); err != nil {
+			t.logger.Error(fmt.Sprintf("Failed to mark gap %d filled: %v", id, err))
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package gaps
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+type fakeRecorder struct {
+	mu     sync.Mutex
+	gaps   []models.DataGap
+	filled chan int
+}
+
+func (r *fakeRecorder) RecordGap(_ context.Context, gap models.DataGap) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gaps = append(r.gaps, gap)
+	return int64(len(r.gaps)), nil
+}
+
+func (r *fakeRecorder) MarkGapFilled(_ context.Context, _ int64, records int) error {
+	r.filled <- records
+	return nil
+}
+
+type fakeFiller struct {
+	records []models.MarketData
+}
+
+func (f fakeFiller) FillGap(context.Context, models.DataGap) ([]models.MarketData, error) {
+	return f.records, nil
+}
+
+func TestTrackerRecordsAndBackfillsGap(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rec := &fakeRecorder{filled: make(chan int, 1)}
+	filler := fakeFiller{records: []models.MarketData{
+		{Name: "NIFTY", Timestamp: 1000},  // boundary, dropped
+		{Name: "NIFTY", Timestamp: 3000},  // inside
+		{Name: "NIFTY", Timestamp: 4000},  // inside
+		{Name: "NIFTY", Timestamp: 6000},  // boundary, dropped
+		{Name: "SENSEX", Timestamp: 3000}, // other symbol, dropped
+	}}
+	out := make(chan models.MarketData, 10)
+	enrich := func(m *models.MarketData) { m.Feed = "primary" }
+	tr := NewTracker("primary", time.Second, filler, rec, out, enrich)
+
+	tr.Observe(ctx, models.MarketData{Name: "NIFTY", Timestamp: 1000})
+	tr.Observe(ctx, models.MarketData{Name: "NIFTY", Timestamp: 1500}) // within threshold
+	tr.Observe(ctx, models.MarketData{Name: "NIFTY", Timestamp: 6000})
+
+	select {
+	case n := <-rec.filled:
+		if n != 2 {
+			t.Errorf("marked %d records filled, want 2", n)
+		}
+	case <-ctx.Done():
+		t.Fatal("gap was not backfilled")
+	}
+
+	rec.mu.Lock()
+	gaps := rec.gaps
+	rec.mu.Unlock()
+	if len(gaps) != 1 {
+		t.Fatalf("recorded %d gaps, want 1", len(gaps))
+	}
+	if g := gaps[0]; g.Feed != "primary" || g.Symbol != "NIFTY" || g.Kind != "timestamp" || g.From != 1500 || g.To != 6000 {
+		t.Errorf("recorded gap %+v", g)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("emitted %d records, want 2", len(out))
+	}
+	for _, want := range []int64{3000, 4000} {
+		m := <-out
+		if m.Timestamp != want || m.Feed != "primary" {
+			t.Errorf("emitted %+v, want timestamp %d from feed primary", m, want)
+		}
+	}
+}
+
+func TestTrackerIgnoresGapBelowThreshold(t *testing.T) {
+	rec := &fakeRecorder{filled: make(chan int, 1)}
+	tr := NewTracker("primary", time.Second, nil, rec, nil, nil)
+
+	tr.Observe(context.Background(), models.MarketData{Name: "NIFTY", Timestamp: 1000})
+	tr.Observe(context.Background(), models.MarketData{Name: "NIFTY", Timestamp: 2000})
+	time.Sleep(50 * time.Millisecond)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.gaps) != 0 {
+		t.Errorf("recorded %+v for a gap at the threshold", rec.gaps)
+	}
+}
@@ -0,0 +1,260 @@
+package instruments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	common "ws_ingestor/internal/app/common/exception_handler"
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Source loads the full instrument master, e.g. storage.Store.
+type Source interface {
+	LoadInstruments(ctx context.Context) ([]models.Instrument, error)
+}
+
+// Writer is a Source that can also persist instruments.
+type Writer interface {
+	Source
+	UpsertInstruments(ctx context.Context, list []models.Instrument) error
+}
+
+// index is an immutable snapshot of the instrument master.
+type index struct {
+	bySymbol     map[string]models.Instrument
+	byExchange   map[string][]string
+	byUnderlying map[string][]string // option symbols per underlying
+}
+
+func buildIndex(list []models.Instrument) *index {
+	idx := &index{
+		bySymbol:     make(map[string]models.Instrument, len(list)),
+		byExchange:   make(map[string][]string),
+		byUnderlying: make(map[string][]string),
+	}
+	for _, in := range list {
+		idx.bySymbol[in.Symbol] = in
+		idx.byExchange[in.Exchange] = append(idx.byExchange[in.Exchange], in.Symbol)
+		if u, ok := underlyingOf(in); ok && in.Active {
+			idx.byUnderlying[u] = append(idx.byUnderlying[u], in.Symbol)
+		}
+	}
+	for _, syms := range idx.byExchange {
+		sort.Strings(syms)
+	}
+	for _, syms := range idx.byUnderlying {
+		sort.Strings(syms)
+	}
+	return idx
+}
+
+// Catalog is the in-memory, indexed cache of the instrument master. Reads go
+// against an immutable snapshot that Reload swaps in one step.
+type Catalog struct {
+	mu    sync.RWMutex
+	idx   *index
+	specs map[string]Spec   // file overrides, highest priority
+	rules map[string]string // exchange -> ExpiryRule
+
+	source Source
+	logger *logrus.Logger
+}
+
+// NewCatalog returns an empty catalog; source may be nil when instruments
+// are only set with Replace.
+func NewCatalog(source Source) *Catalog {
+	return &Catalog{
+		idx:    buildIndex(nil),
+		specs:  make(map[string]Spec),
+		rules:  make(map[string]string),
+		source: source,
+		logger: logger.GetLogger(),
+	}
+}
+
+func (c *Catalog) snapshot() *index {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.idx
+}
+
+// Replace swaps in a new instrument list.
+func (c *Catalog) Replace(list []models.Instrument) {
+	idx := buildIndex(list)
+	c.mu.Lock()
+	c.idx = idx
+	c.mu.Unlock()
+	metrics.InstrumentsLoaded.Set(float64(len(list)))
+}
+
+// Reload re-reads the instrument master from the source.
+func (c *Catalog) Reload(ctx context.Context) error {
+	if c.source == nil {
+		return nil
+	}
+	list, err := c.source.LoadInstruments(ctx)
+	if err != nil {
+		return err
+	}
+	c.Replace(list)
+	return nil
+}
+
+// Bootstrap performs the initial load. An empty master is seeded from the
+// legacy symbol constants when the source is writable.
+func (c *Catalog) Bootstrap(ctx context.Context) error {
+	if err := c.Reload(ctx); err != nil {
+		return err
+	}
+	if c.Len() > 0 {
+		return nil
+	}
+	w, ok := c.source.(Writer)
+	if !ok {
+		return nil
+	}
+	seed := SeedFromConstants()
+	if err := w.UpsertInstruments(ctx, seed); err != nil {
+		return err
+	}
+	c.logger.Info(fmt.Sprintf("Seeded instrument master with %d legacy symbols", len(seed)))
+	return c.Reload(ctx)
+}
+
+// Upsert persists instruments through a writable source and reloads.
+func (c *Catalog) Upsert(ctx context.Context, list ...models.Instrument) error {
+	w, ok := c.source.(Writer)
+	if !ok {
+		return fmt.Errorf("instrument source is read-only")
+	}
+	if err := w.UpsertInstruments(ctx, list); err != nil {
+		return err
+	}
+	return c.Reload(ctx)
+}
+
+// Run reloads the catalog every interval until ctx is done.
+func (c *Catalog) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Reload(ctx); err != nil {
+				c.logger.Error(fmt.Sprintf("Failed to reload instruments: %v", err))
+				metrics.ErrorsTotal.WithLabelValues("instruments_reload").Inc()
+			}
+		}
+	}
+}
+
+// Lookup returns the instrument for symbol.
+func (c *Catalog) Lookup(symbol string) (models.Instrument, bool) {
+	in, ok := c.snapshot().bySymbol[symbol]
+	return in, ok
+}
+
+// Exchange returns the exchange code of symbol.
+func (c *Catalog) Exchange(symbol string) (string, bool) {
+	in, ok := c.snapshot().bySymbol[symbol]
+	return in.Exchange, ok
+}
+
+// ByExchange lists instruments of one exchange in symbol order.
+func (c *Catalog) ByExchange(exchange string) []models.Instrument {
+	idx := c.snapshot()
+	out := make([]models.Instrument, 0, len(idx.byExchange[exchange]))
+	for _, s := range idx.byExchange[exchange] {
+		out = append(out, idx.bySymbol[s])
+	}
+	return out
+}
+
+// All lists every instrument in symbol order.
+func (c *Catalog) All() []models.Instrument {
+	idx := c.snapshot()
+	out := make([]models.Instrument, 0, len(idx.bySymbol))
+	for _, in := range idx.bySymbol {
+		out = append(out, in)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+func (c *Catalog) Len() int {
+	return len(c.snapshot().bySymbol)
+}
+
+// LoadSpecs reads a JSON array of Spec overrides into the catalog.
+func (c *Catalog) LoadSpecs(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Failed to read instrument specs %s", path), err)
+	}
+	var specs []Spec
+	if err := json.Unmarshal(raw, &specs); err != nil {
+		return common.NewCustomError(common.ErrConfigLoad, fmt.Sprintf("Failed to parse instrument specs %s", path), err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range specs {
+		c.specs[s.Symbol] = s
+	}
+	return nil
+}
+
+// Spec returns price metadata for symbol: a file override, else the
+// instrument master, else the exchange default.
+func (c *Catalog) Spec(symbol, exchange string) Spec {
+	c.mu.RLock()
+	s, ok := c.specs[symbol]
+	idx := c.idx
+	c.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	if in, ok := idx.bySymbol[symbol]; ok && in.Precision > 0 {
+		return Spec{Symbol: symbol, Exchange: in.Exchange, TickSize: in.TickSize, Precision: in.Precision}
+	}
+	return defaultSpec(symbol, exchange)
+}
+
+// SetExpiryRules sets the per-exchange ExpiryRule used for contracts whose
+// expiry is not in the instrument master.
+func (c *Catalog) SetExpiryRules(rules map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = rules
+}
+
+// ContractExpiry returns the expiry date of a monthly contract: the master's
+// expiry for symbol, else the exchange's expiry rule for year/month.
+func (c *Catalog) ContractExpiry(symbol, exchange string, year int, month time.Month) (time.Time, error) {
+	c.mu.RLock()
+	in, ok := c.idx.bySymbol[symbol]
+	rule, hasRule := c.rules[exchange]
+	c.mu.RUnlock()
+	if ok && in.Expiry != nil {
+		y, m, d := in.Expiry.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, Location), nil
+	}
+	if !hasRule {
+		rule = "last-business-day"
+	}
+	return ExpiryRule(rule, year, month, Location)
+}
@@ -0,0 +1,65 @@
+package instruments
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Location is the exchange time zone used for derivative expiries.
+var Location = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Kolkata"); err == nil {
+		return loc
+	}
+	return time.FixedZone("IST", 5*3600+1800)
+}()
+
+// ExpiryRule computes a contract's expiry date when the instrument master
+// does not carry one. Supported rules: last-monday .. last-friday,
+// last-business-day and day-N (the Nth calendar day, moved back to a weekday).
+func ExpiryRule(rule string, year int, month time.Month, loc *time.Location) (time.Time, error) {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1)
+
+	switch {
+	case rule == "last-business-day":
+		return backToWeekday(last), nil
+	case strings.HasPrefix(rule, "last-"):
+		wd, ok := weekdays[strings.TrimPrefix(rule, "last-")]
+		if !ok {
+			break
+		}
+		d := last
+		for d.Weekday() != wd {
+			d = d.AddDate(0, 0, -1)
+		}
+		return d, nil
+	case strings.HasPrefix(rule, "day-"):
+		n, err := strconv.Atoi(strings.TrimPrefix(rule, "day-"))
+		if err != nil || n < 1 || n > 31 {
+			break
+		}
+		d := first.AddDate(0, 0, n-1)
+		if d.Month() != month {
+			d = last
+		}
+		return backToWeekday(d), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown expiry rule %q", rule)
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+}
+
+func backToWeekday(d time.Time) time.Time {
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
@@ -0,0 +1,48 @@
+package instruments
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// NewListHandler serves GET /instruments[?symbol=|?exchange=].
+func NewListHandler(c *Catalog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var list []models.Instrument
+		q := r.URL.Query()
+		switch {
+		case q.Get("symbol") != "":
+			in, ok := c.Lookup(q.Get("symbol"))
+			if !ok {
+				http.Error(w, "unknown symbol", http.StatusNotFound)
+				return
+			}
+			list = []models.Instrument{in}
+		case q.Get("exchange") != "":
+			list = c.ByExchange(q.Get("exchange"))
+		default:
+			list = c.All()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+// NewReloadHandler serves POST /instruments/reload.
+func NewReloadHandler(c *Catalog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := c.Reload(r.Context()); err != nil {
+			http.Error(w, "reload failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"instruments": c.Len()})
+	}
+}
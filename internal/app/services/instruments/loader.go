@@ -0,0 +1,195 @@
+package instruments
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// record is the import shape; expiry is a plain date.
+type record struct {
+	Symbol     string          `json:"symbol"`
+	Exchange   string          `json:"exchange"`
+	AssetClass string          `json:"asset_class"`
+	TickSize   models.Decimal  `json:"tick_size"`
+	LotSize    models.Decimal  `json:"lot_size"`
+	Precision  int             `json:"precision"`
+	Expiry     string          `json:"expiry"`
+	Currency   string          `json:"currency"`
+	Active     *bool           `json:"active"`
+	Underlying string          `json:"underlying"`
+	Strike     *models.Decimal `json:"strike"`
+	Right      string          `json:"right"`
+}
+
+func (r record) instrument() (models.Instrument, error) {
+	in := models.Instrument{
+		Symbol:     strings.TrimSpace(r.Symbol),
+		Exchange:   strings.ToLower(strings.TrimSpace(r.Exchange)),
+		AssetClass: r.AssetClass,
+		TickSize:   r.TickSize,
+		LotSize:    r.LotSize,
+		Precision:  r.Precision,
+		Currency:   r.Currency,
+		Active:     r.Active == nil || *r.Active,
+		Underlying: strings.TrimSpace(r.Underlying),
+		Strike:     r.Strike,
+		Right:      strings.ToUpper(strings.TrimSpace(r.Right)),
+	}
+	if in.Symbol == "" || in.Exchange == "" {
+		return in, fmt.Errorf("symbol and exchange are required")
+	}
+	if r.Expiry != "" {
+		t, err := time.Parse(dateLayout, r.Expiry)
+		if err != nil {
+			return in, fmt.Errorf("invalid expiry %q for %s", r.Expiry, in.Symbol)
+		}
+		in.Expiry = &t
+	}
+	// Option fields not given explicitly come from the tradingsymbol
+	if o, ok := ParseOption(in.Symbol); ok {
+		if in.Underlying == "" {
+			in.Underlying = o.Underlying
+		}
+		if in.Strike == nil {
+			in.Strike = &o.Strike
+		}
+		if in.Right == "" {
+			in.Right = string(o.Right)
+		}
+		if in.AssetClass == "" {
+			in.AssetClass = "option"
+		}
+	}
+	if in.Right != "" && in.Right != string(models.Call) && in.Right != string(models.Put) {
+		return in, fmt.Errorf("invalid right %q for %s (want CE or PE)", in.Right, in.Symbol)
+	}
+	if in.Precision == 0 && in.TickSize.Sign() > 0 {
+		in.Precision = in.TickSize.Scale()
+	}
+	return in, nil
+}
+
+// ReadFile imports instruments from a .csv or .json file.
+func ReadFile(path string) ([]models.Instrument, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ReadCSV(f)
+	case ".json":
+		return ReadJSON(f)
+	}
+	return nil, fmt.Errorf("unsupported instrument file %s (want .csv or .json)", path)
+}
+
+// ReadJSON reads an array of instrument records.
+func ReadJSON(r io.Reader) ([]models.Instrument, error) {
+	var recs []record
+	if err := json.NewDecoder(r).Decode(&recs); err != nil {
+		return nil, err
+	}
+	out := make([]models.Instrument, 0, len(recs))
+	for i, rec := range recs {
+		in, err := rec.instrument()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		out = append(out, in)
+	}
+	return out, nil
+}
+
+// ReadCSV reads instruments from a CSV file whose header names the columns
+// symbol, exchange, asset_class, tick_size, lot_size, precision, expiry,
+// currency, active, underlying, strike and right, in any order. Only symbol
+// and exchange are required.
+func ReadCSV(r io.Reader) ([]models.Instrument, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	get := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var out []models.Instrument
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rec := record{
+			Symbol:     get(row, "symbol"),
+			Exchange:   get(row, "exchange"),
+			AssetClass: get(row, "asset_class"),
+			Expiry:     get(row, "expiry"),
+			Currency:   get(row, "currency"),
+			Underlying: get(row, "underlying"),
+			Right:      get(row, "right"),
+		}
+		if v := get(row, "tick_size"); v != "" {
+			if rec.TickSize, err = models.ParseDecimal(v); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		if v := get(row, "lot_size"); v != "" {
+			if rec.LotSize, err = models.ParseDecimal(v); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		if v := get(row, "strike"); v != "" {
+			d, err := models.ParseDecimal(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rec.Strike = &d
+		}
+		if v := get(row, "precision"); v != "" {
+			if rec.Precision, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("line %d: invalid precision %q", line, v)
+			}
+		}
+		if v := get(row, "active"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid active %q", line, v)
+			}
+			rec.Active = &b
+		}
+
+		in, err := rec.instrument()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, in)
+	}
+	return out, nil
+}
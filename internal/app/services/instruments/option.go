@@ -0,0 +1,132 @@
+package instruments
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+var (
+	monthCodes = map[string]time.Month{
+		"JAN": time.January, "FEB": time.February, "MAR": time.March, "APR": time.April,
+		"MAY": time.May, "JUN": time.June, "JUL": time.July, "AUG": time.August,
+		"SEP": time.September, "OCT": time.October, "NOV": time.November, "DEC": time.December,
+	}
+	// Weekly expiries code the month as 1-9, O, N, D followed by the day.
+	weeklyMonths = map[byte]time.Month{'O': time.October, 'N': time.November, 'D': time.December}
+
+	monthlyOption = regexp.MustCompile(`^(.+?)(\d{2})(JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)(\d+(?:\.\d+)?)(CE|PE)$`)
+	weeklyOption  = regexp.MustCompile(`^(.+?)(\d{2})([1-9OND])(\d{2})(\d+(?:\.\d+)?)(CE|PE)$`)
+)
+
+// OptionSymbol is a parsed option tradingsymbol. Day is zero for monthly
+// contracts, whose expiry follows the exchange's expiry rule.
+type OptionSymbol struct {
+	Underlying string
+	Year       int
+	Month      time.Month
+	Day        int
+	Strike     models.Decimal
+	Right      models.OptionRight
+}
+
+// ParseOption parses NSE-style option symbols: monthly
+// <UNDERLYING><YY><MON><STRIKE><CE|PE> (NIFTY25DEC24000CE) and weekly
+// <UNDERLYING><YY><M><DD><STRIKE><CE|PE> (NIFTY25D1624000CE).
+func ParseOption(symbol string) (OptionSymbol, bool) {
+	if m := monthlyOption.FindStringSubmatch(symbol); m != nil {
+		strike, err := models.ParseDecimal(m[4])
+		if err != nil {
+			return OptionSymbol{}, false
+		}
+		yy, _ := strconv.Atoi(m[2])
+		return OptionSymbol{
+			Underlying: m[1],
+			Year:       2000 + yy,
+			Month:      monthCodes[m[3]],
+			Strike:     strike,
+			Right:      models.OptionRight(m[5]),
+		}, true
+	}
+	if m := weeklyOption.FindStringSubmatch(symbol); m != nil {
+		strike, err := models.ParseDecimal(m[5])
+		if err != nil {
+			return OptionSymbol{}, false
+		}
+		yy, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[4])
+		month, ok := weeklyMonths[m[3][0]]
+		if !ok {
+			month = time.Month(m[3][0] - '0')
+		}
+		if day < 1 || day > 31 {
+			return OptionSymbol{}, false
+		}
+		return OptionSymbol{
+			Underlying: m[1],
+			Year:       2000 + yy,
+			Month:      month,
+			Day:        day,
+			Strike:     strike,
+			Right:      models.OptionRight(m[6]),
+		}, true
+	}
+	return OptionSymbol{}, false
+}
+
+// underlyingOf returns the option underlying of an instrument, if any.
+func underlyingOf(in models.Instrument) (string, bool) {
+	if in.Underlying != "" && in.Right != "" {
+		return in.Underlying, true
+	}
+	if o, ok := ParseOption(in.Symbol); ok {
+		return o.Underlying, true
+	}
+	return "", false
+}
+
+// Option resolves symbol to an option contract, preferring the instrument
+// master's underlying, strike, right and expiry over the parsed symbol.
+func (c *Catalog) Option(symbol string) (models.OptionContract, bool) {
+	in, known := c.Lookup(symbol)
+	parsed, ok := ParseOption(symbol)
+
+	var out models.OptionContract
+	switch {
+	case known && in.Right != "" && in.Strike != nil && in.Underlying != "":
+		out = models.OptionContract{Underlying: in.Underlying, Strike: *in.Strike, Right: models.OptionRight(strings.ToUpper(in.Right))}
+	case ok:
+		out = models.OptionContract{Underlying: parsed.Underlying, Strike: parsed.Strike, Right: parsed.Right}
+	default:
+		return out, false
+	}
+
+	switch {
+	case known && in.Expiry != nil:
+		y, m, d := in.Expiry.Date()
+		out.Expiry = time.Date(y, m, d, 0, 0, 0, 0, Location)
+	case ok && parsed.Day > 0:
+		out.Expiry = time.Date(parsed.Year, parsed.Month, parsed.Day, 0, 0, 0, 0, Location)
+	case ok:
+		exch := in.Exchange
+		if exch == "" {
+			exch = "cepe"
+		}
+		expiry, err := c.ContractExpiry(symbol, exch, parsed.Year, parsed.Month)
+		if err != nil {
+			return out, false
+		}
+		out.Expiry = expiry
+	default:
+		return out, false
+	}
+	return out, true
+}
+
+// Options lists the option symbols of underlying in the instrument master.
+func (c *Catalog) Options(underlying string) []string {
+	return append([]string(nil), c.snapshot().byUnderlying[underlying]...)
+}
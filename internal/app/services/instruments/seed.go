@@ -0,0 +1,70 @@
+package instruments
+
+import (
+	"strings"
+
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+)
+
+var assetClasses = map[string]string{
+	"nse":     "future",
+	"mcx":     "future",
+	"gift":    "future",
+	"other":   "future",
+	"cepe":    "option",
+	"comex":   "commodity",
+	"forex":   "fx",
+	"crypto":  "crypto",
+	"usstock": "equity",
+}
+
+var currencies = map[string]string{
+	"nse":     "INR",
+	"mcx":     "INR",
+	"cepe":    "INR",
+	"gift":    "USD",
+	"comex":   "USD",
+	"other":   "USD",
+	"usstock": "USD",
+}
+
+// SeedFromConstants converts the legacy hard-coded symbol lists into
+// instruments. It is only used to bootstrap an empty instrument master.
+func SeedFromConstants() []models.Instrument {
+	all := constants.GetAllSymbols()
+	out := make([]models.Instrument, 0, len(all))
+	for sym, exch := range all {
+		spec := defaultSpec(sym, exch)
+		out = append(out, models.Instrument{
+			Symbol:     sym,
+			Exchange:   exch,
+			AssetClass: assetClasses[exch],
+			TickSize:   spec.TickSize,
+			LotSize:    models.NewDecimal(1, 0),
+			Precision:  spec.Precision,
+			Currency:   seedCurrency(sym, exch),
+			Active:     true,
+		})
+	}
+	return out
+}
+
+func seedCurrency(symbol, exchange string) string {
+	if c, ok := currencies[exchange]; ok {
+		return c
+	}
+	switch exchange {
+	case "forex":
+		if len(symbol) == 6 {
+			return symbol[3:]
+		}
+	case "crypto":
+		for _, q := range []string{"USDT", "USDC", "USD", "BTC", "ETH"} {
+			if strings.HasSuffix(symbol, q) {
+				return q
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package instruments
+
+import (
+	"strings"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// Spec is the price metadata for one instrument.
+type Spec struct {
+	Symbol    string         `json:"symbol"`
+	Exchange  string         `json:"exchange"`
+	TickSize  models.Decimal `json:"tick_size"`
+	Precision int            `json:"precision"`
+}
+
+// Quantize rounds a price to the instrument's precision and tick size.
+func (s Spec) Quantize(p models.Decimal) models.Decimal {
+	return p.RoundToTick(s.TickSize).Round(s.Precision)
+}
+
+func mustDecimal(s string) models.Decimal {
+	d, err := models.ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// exchangeDefaults applies to symbols without their own spec.
+var exchangeDefaults = map[string]Spec{
+	"nse":     {TickSize: mustDecimal("0.05"), Precision: 2},
+	"mcx":     {TickSize: mustDecimal("0.01"), Precision: 2},
+	"cepe":    {TickSize: mustDecimal("0.05"), Precision: 2},
+	"gift":    {TickSize: mustDecimal("0.5"), Precision: 2},
+	"comex":   {TickSize: mustDecimal("0.01"), Precision: 2},
+	"other":   {TickSize: mustDecimal("0.01"), Precision: 2},
+	"forex":   {TickSize: mustDecimal("0.00001"), Precision: 5},
+	"crypto":  {TickSize: mustDecimal("0.00000001"), Precision: 8},
+	"usstock": {TickSize: mustDecimal("0.01"), Precision: 2},
+}
+
+var fallbackSpec = Spec{Precision: 8}
+
+// defaultSpec returns the exchange-level spec for symbols without metadata.
+func defaultSpec(symbol, exchange string) Spec {
+	s, ok := exchangeDefaults[exchange]
+	if !ok {
+		s = fallbackSpec
+	}
+	// Yen crosses quote to 3 decimals
+	if exchange == "forex" && strings.HasSuffix(symbol, "JPY") {
+		s = Spec{TickSize: mustDecimal("0.001"), Precision: 3}
+	}
+	s.Symbol = symbol
+	s.Exchange = exchange
+	return s
+}
@@ -0,0 +1,192 @@
+package options
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// Request selects part of an option chain: Expiry is "nearest" (default),
+// "next" or a YYYY-MM-DD date; Strikes is the number of strikes kept on each
+// side of the at-the-money strike, 0 for the whole chain.
+type Request struct {
+	Underlying string `json:"underlying"`
+	Expiry     string `json:"expiry,omitempty"`
+	Strikes    int    `json:"strikes,omitempty"`
+}
+
+// Key identifies equivalent requests.
+func (r Request) Key() string {
+	return fmt.Sprintf("%s|%s|%d", r.Underlying, r.Expiry, r.Strikes)
+}
+
+// Row is one strike of a chain with its call and put.
+type Row struct {
+	Strike models.Decimal     `json:"strike"`
+	Call   string             `json:"call,omitempty"`
+	Put    string             `json:"put,omitempty"`
+	CE     *models.MarketData `json:"ce,omitempty"`
+	PE     *models.MarketData `json:"pe,omitempty"`
+}
+
+// Chain is a resolved slice of an option chain around the money.
+type Chain struct {
+	Underlying string          `json:"underlying"`
+	Expiry     string          `json:"expiry"`
+	Expiries   []string        `json:"expiries"`
+	Spot       *models.Decimal `json:"spot,omitempty"`
+	ATM        models.Decimal  `json:"atm"`
+	Rows       []Row           `json:"strikes"`
+}
+
+// Symbols lists the option symbols in the chain.
+func (c Chain) Symbols() []string {
+	out := make([]string, 0, 2*len(c.Rows))
+	for _, r := range c.Rows {
+		if r.Call != "" {
+			out = append(out, r.Call)
+		}
+		if r.Put != "" {
+			out = append(out, r.Put)
+		}
+	}
+	return out
+}
+
+// Resolve picks the expiry and the strikes around the underlying's latest
+// price from the instrument master. Without a price the middle strike is
+// used as at-the-money.
+func (s *Service) Resolve(ctx context.Context, req Request, now time.Time) (Chain, error) {
+	type leg struct{ call, put string }
+	byExpiry := make(map[string]map[string]*leg)
+	strikes := make(map[string]map[string]models.Decimal)
+
+	for _, sym := range s.catalog.Options(req.Underlying) {
+		opt, ok := s.catalog.Option(sym)
+		if !ok || !now.Before(opt.Expiry.AddDate(0, 0, 1)) {
+			continue
+		}
+		exp := opt.ExpiryDate()
+		if byExpiry[exp] == nil {
+			byExpiry[exp] = make(map[string]*leg)
+			strikes[exp] = make(map[string]models.Decimal)
+		}
+		k := opt.Strike.String()
+		l := byExpiry[exp][k]
+		if l == nil {
+			l = &leg{}
+			byExpiry[exp][k] = l
+			strikes[exp][k] = opt.Strike
+		}
+		if opt.Right == models.Call {
+			l.call = sym
+		} else {
+			l.put = sym
+		}
+	}
+	if len(byExpiry) == 0 {
+		return Chain{}, fmt.Errorf("no live options for %q", req.Underlying)
+	}
+
+	expiries := make([]string, 0, len(byExpiry))
+	for exp := range byExpiry {
+		expiries = append(expiries, exp)
+	}
+	sort.Strings(expiries)
+
+	var expiry string
+	switch req.Expiry {
+	case "", "nearest":
+		expiry = expiries[0]
+	case "next":
+		if len(expiries) < 2 {
+			return Chain{}, fmt.Errorf("no next expiry for %q", req.Underlying)
+		}
+		expiry = expiries[1]
+	default:
+		if _, ok := byExpiry[req.Expiry]; !ok {
+			return Chain{}, fmt.Errorf("no %s expiry for %q", req.Expiry, req.Underlying)
+		}
+		expiry = req.Expiry
+	}
+
+	ladder := make([]models.Decimal, 0, len(strikes[expiry]))
+	for _, k := range strikes[expiry] {
+		ladder = append(ladder, k)
+	}
+	sort.Slice(ladder, func(i, j int) bool { return ladder[i].Cmp(ladder[j]) < 0 })
+
+	chain := Chain{Underlying: req.Underlying, Expiry: expiry, Expiries: expiries}
+	atm := len(ladder) / 2
+	if spot, ok := s.spot(ctx, req.Underlying); ok {
+		chain.Spot = &spot
+		atm = nearest(ladder, spot)
+	}
+	chain.ATM = ladder[atm]
+
+	lo, hi := 0, len(ladder)-1
+	if req.Strikes > 0 {
+		lo, hi = max(0, atm-req.Strikes), min(hi, atm+req.Strikes)
+	}
+	for _, k := range ladder[lo : hi+1] {
+		l := byExpiry[expiry][k.String()]
+		chain.Rows = append(chain.Rows, Row{Strike: k, Call: l.call, Put: l.put})
+	}
+	return chain, nil
+}
+
+// Snapshot resolves req and fills every row from the cached chain.
+func (s *Service) Snapshot(ctx context.Context, req Request, now time.Time) (Chain, error) {
+	chain, err := s.Resolve(ctx, req, now)
+	if err != nil {
+		return chain, err
+	}
+	ticks, err := s.cache.GetChain(ctx, chain.Underlying, chain.Expiry)
+	if err != nil {
+		return chain, err
+	}
+	for i := range chain.Rows {
+		r := &chain.Rows[i]
+		if t, ok := ticks[r.Call]; ok {
+			r.CE = &t
+		}
+		if t, ok := ticks[r.Put]; ok {
+			r.PE = &t
+		}
+	}
+	return chain, nil
+}
+
+// spot returns the underlying's latest price from the cache, trying the
+// underlying itself and then its continuous futures alias.
+func (s *Service) spot(ctx context.Context, underlying string) (models.Decimal, bool) {
+	for _, sym := range []string{underlying, underlying + s.aliasSuffix} {
+		m, ok, err := s.cache.Get(ctx, sym)
+		if err != nil || !ok {
+			continue
+		}
+		if p, ok := m.Price(); ok {
+			return p, true
+		}
+	}
+	return models.Decimal{}, false
+}
+
+// nearest returns the index of the strike closest to spot.
+func nearest(ladder []models.Decimal, spot models.Decimal) int {
+	best := 0
+	var bestDiff models.Decimal
+	for i, k := range ladder {
+		diff := k.Sub(spot)
+		if diff.Sign() < 0 {
+			diff = spot.Sub(k)
+		}
+		if i == 0 || diff.Cmp(bestDiff) < 0 {
+			best, bestDiff = i, diff
+		}
+	}
+	return best
+}
@@ -0,0 +1,36 @@
+package options
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NewChainHandler serves GET /chain?underlying=NIFTY[&expiry=nearest|next|YYYY-MM-DD][&strikes=N].
+func NewChainHandler(s *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		req := Request{Underlying: q.Get("underlying"), Expiry: q.Get("expiry")}
+		if req.Underlying == "" {
+			http.Error(w, "underlying is required", http.StatusBadRequest)
+			return
+		}
+		if v := q.Get("strikes"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid strikes", http.StatusBadRequest)
+				return
+			}
+			req.Strikes = n
+		}
+
+		chain, err := s.Snapshot(r.Context(), req, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(chain)
+	}
+}
@@ -0,0 +1,150 @@
+package options
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/services/instruments"
+	"ws_ingestor/internal/app/services/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Subscriber manages upstream subscriptions, e.g. websocket.Registry.
+type Subscriber interface {
+	Subscriptions() map[string][]string
+	Subscribe(feed string, symbols ...string) error
+	Unsubscribe(feed string, symbols ...string) error
+}
+
+type watch struct {
+	req  Request
+	refs int
+}
+
+// Service resolves option chains and keeps the strikes watched by clients
+// subscribed upstream as the underlying moves.
+type Service struct {
+	catalog     *instruments.Catalog
+	cache       *storage.CacheService
+	subs        Subscriber
+	feed        string
+	aliasSuffix string
+	logger      *logrus.Logger
+
+	mu      sync.Mutex
+	watches map[string]*watch
+	owned   map[string]struct{} // symbols this service subscribed upstream
+}
+
+// NewService builds a chain service subscribing strikes on feed.
+// aliasSuffix names the continuous futures alias used as a price fallback.
+func NewService(catalog *instruments.Catalog, cache *storage.CacheService, subs Subscriber, feed, aliasSuffix string) *Service {
+	return &Service{
+		catalog:     catalog,
+		cache:       cache,
+		subs:        subs,
+		feed:        feed,
+		aliasSuffix: aliasSuffix,
+		logger:      logger.GetLogger(),
+		watches:     make(map[string]*watch),
+		owned:       make(map[string]struct{}),
+	}
+}
+
+// Watch registers interest in req; its strikes are kept subscribed until
+// the matching Unwatch.
+func (s *Service) Watch(req Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.watches[req.Key()]
+	if !ok {
+		w = &watch{req: req}
+		s.watches[req.Key()] = w
+	}
+	w.refs++
+}
+
+func (s *Service) Unwatch(req Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w, ok := s.watches[req.Key()]; ok {
+		if w.refs--; w.refs <= 0 {
+			delete(s.watches, req.Key())
+		}
+	}
+}
+
+// Run re-resolves watched chains every interval and adjusts the upstream
+// subscription set. A non-positive interval disables the refresh.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sync(ctx, time.Now())
+		}
+	}
+}
+
+func (s *Service) sync(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	reqs := make([]Request, 0, len(s.watches))
+	for _, w := range s.watches {
+		reqs = append(reqs, w.req)
+	}
+	s.mu.Unlock()
+
+	want := make(map[string]struct{})
+	for _, req := range reqs {
+		chain, err := s.Resolve(ctx, req, now)
+		if err != nil {
+			s.logger.Warn(fmt.Sprintf("Failed to resolve option chain %s: %v", req.Underlying, err))
+			continue
+		}
+		for _, sym := range chain.Symbols() {
+			want[sym] = struct{}{}
+		}
+	}
+
+	current := s.subs.Subscriptions()[s.feed]
+	var add, drop []string
+	for sym := range want {
+		if !slices.Contains(current, sym) {
+			add = append(add, sym)
+		}
+	}
+
+	s.mu.Lock()
+	for sym := range s.owned {
+		if _, ok := want[sym]; !ok {
+			drop = append(drop, sym)
+			delete(s.owned, sym)
+		}
+	}
+	for _, sym := range add {
+		s.owned[sym] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	if len(add) > 0 {
+		if err := s.subs.Subscribe(s.feed, add...); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to subscribe %d option strikes: %v", len(add), err))
+		}
+	}
+	if len(drop) > 0 {
+		if err := s.subs.Unsubscribe(s.feed, drop...); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to unsubscribe %d option strikes: %v", len(drop), err))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package replay
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewHandler serves /replay: GET lists sessions (or one with ?id=), POST
+// creates one from a JSON Spec and DELETE ?id= stops one.
+func NewHandler(m *Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			if id == "" {
+				json.NewEncoder(w).Encode(m.List())
+				return
+			}
+			s, ok := m.Get(id)
+			if !ok {
+				http.Error(w, "unknown replay", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(s.Status())
+
+		case http.MethodPost:
+			var spec Spec
+			if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+				http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			s, err := m.Start(spec)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(s.Status())
+
+		case http.MethodDelete:
+			if !m.Stop(id) {
+				http.Error(w, "unknown replay", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package replay re-streams historical ticks, from Postgres or an exported
+// file, at their original pace, faster, or as fast as possible. Replayed
+// ticks go to their own session rather than the live pipeline, so stores,
+// caches and live clients are never touched.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/export"
+	"ws_ingestor/internal/app/services/storage"
+)
+
+// Source yields historical ticks in timestamp order; Next returns io.EOF
+// after the last one.
+type Source interface {
+	Next(ctx context.Context) (models.MarketData, error)
+	Close() error
+}
+
+// pageSize is how many ticks the Postgres source reads per query; the
+// connection is released between pages however slow the replay.
+const pageSize = 5000
+
+type storeSource struct {
+	store *storage.Store
+	q     storage.TickQuery
+	page  []models.MarketData
+	last  bool
+}
+
+// NewStoreSource reads the ticks in r from the market data table.
+func NewStoreSource(store *storage.Store, r storage.Range) Source {
+	return &storeSource{store: store, q: storage.TickQuery{Range: r, Limit: pageSize}}
+}
+
+func (s *storeSource) Next(ctx context.Context) (models.MarketData, error) {
+	if len(s.page) == 0 {
+		if s.last {
+			return models.MarketData{}, io.EOF
+		}
+		page, next, err := s.store.QueryTicks(ctx, s.q)
+		if err != nil {
+			return models.MarketData{}, err
+		}
+		s.page, s.q.After, s.last = page, next, next == nil
+		if len(s.page) == 0 {
+			return models.MarketData{}, io.EOF
+		}
+	}
+	m := s.page[0]
+	s.page = s.page[1:]
+	return m, nil
+}
+
+func (s *storeSource) Close() error { return nil }
+
+type fileSource struct {
+	r *export.Reader
+}
+
+// NewFileSource reads a file written by a ticks export.
+func NewFileSource(path string) (Source, error) {
+	r, err := export.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{r: r}, nil
+}
+
+func (s *fileSource) Next(ctx context.Context) (models.MarketData, error) {
+	if err := ctx.Err(); err != nil {
+		return models.MarketData{}, err
+	}
+	return s.r.Next()
+}
+
+func (s *fileSource) Close() error { return s.r.Close() }
+
+// Pace is the replay speed relative to the original tick times; zero means
+// as fast as possible.
+type Pace float64
+
+// ParsePace reads "realtime", "asap" or a speed-up such as "10x".
+func ParsePace(s string) (Pace, error) {
+	switch s {
+	case "", "realtime":
+		return 1, nil
+	case "asap":
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || n <= 0 || !strings.HasSuffix(s, "x") {
+		return 0, fmt.Errorf("invalid pace %q (want realtime, asap or e.g. 10x)", s)
+	}
+	return Pace(n), nil
+}
+
+func (p Pace) String() string {
+	switch p {
+	case 0:
+		return "asap"
+	case 1:
+		return "realtime"
+	}
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "x"
+}
+
+// Replayer plays a Source into a channel, in the role an Ingestor has for
+// live feeds.
+type Replayer struct {
+	src  Source
+	pace Pace
+}
+
+func NewReplayer(src Source, pace Pace) *Replayer {
+	return &Replayer{src: src, pace: pace}
+}
+
+// Run sends every tick of the source to out, spaced like the original tick
+// times divided by the pace, and returns once the source is exhausted. Ticks
+// out of timestamp order are sent at once.
+func (r *Replayer) Run(ctx context.Context, out chan<- models.MarketData) error {
+	defer r.src.Close()
+	var (
+		first int64
+		start time.Time
+		timer *time.Timer
+	)
+	for {
+		m, err := r.src.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if r.pace > 0 {
+			if start.IsZero() {
+				first, start = m.Timestamp, time.Now()
+			}
+			due := start.Add(time.Duration(float64(m.Timestamp-first) * float64(time.Millisecond) / float64(r.pace)))
+			if wait := time.Until(due); wait > 0 {
+				if timer == nil {
+					timer = time.NewTimer(wait)
+					defer timer.Stop()
+				} else {
+					timer.Reset(wait)
+				}
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+
+		select {
+		case out <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package replay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/storage"
+	"ws_ingestor/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Spec describes a replay session, e.g.
+// {"source":"postgres","symbols":["NIFTY25DECFUT"],"from":"2026-10-15T03:45:00Z","pace":"10x"}.
+type Spec struct {
+	ID      string   `json:"id,omitempty"`
+	Source  string   `json:"source"`         // "postgres" or "file"
+	File    string   `json:"file,omitempty"` // export file, relative to the replay directory
+	Symbols []string `json:"symbols,omitempty"`
+	From    string   `json:"from,omitempty"` // RFC3339 or Unix ms
+	To      string   `json:"to,omitempty"`
+	Pace    string   `json:"pace,omitempty"` // realtime (default), asap or e.g. 10x
+}
+
+// State is the lifecycle of a session: it waits for its first client, plays
+// and then ends as done, failed or stopped. A session no client joins in
+// time fails.
+type State string
+
+const (
+	StateWaiting State = "waiting"
+	StatePlaying State = "playing"
+	StateDone    State = "done"
+	StateFailed  State = "failed"
+	StateStopped State = "stopped"
+)
+
+// Status is the snapshot reported for a session.
+type Status struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Pace      string    `json:"pace"`
+	State     State     `json:"state"`
+	Ticks     int64     `json:"ticks"`
+	Position  int64     `json:"position,omitempty"` // timestamp of the last tick sent
+	Clients   int       `json:"clients"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	EndedAt   time.Time `json:"ended_at,omitzero"`
+}
+
+type subscriber struct {
+	ch   chan models.MarketData
+	gone chan struct{}
+}
+
+// Session replays one source to every client that joins it. Playback starts
+// when the first client joins, and each tick is delivered to every client:
+// a slow client slows the session down rather than missing ticks.
+type Session struct {
+	ID      string
+	spec    Spec
+	pace    Pace
+	src     Source
+	created time.Time
+	timeout time.Duration
+	logger  *logrus.Logger
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	start     chan struct{}
+	startOnce sync.Once
+	done      chan struct{}
+
+	mu       sync.Mutex
+	state    State
+	err      error
+	ticks    int64
+	position int64
+	ended    time.Time
+	subs     map[*subscriber]struct{}
+}
+
+// subscriberBuffer is how many ticks a client may fall behind before it
+// holds the session up.
+const subscriberBuffer = 4096
+
+// Subscribe joins the session. Ticks arrive on the returned channel, which
+// is never closed; Done signals the end of the session. leave must be
+// called when the client goes away.
+func (s *Session) Subscribe() (ticks <-chan models.MarketData, leave func()) {
+	sub := &subscriber{ch: make(chan models.MarketData, subscriberBuffer), gone: make(chan struct{})}
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	s.startOnce.Do(func() { close(s.start) })
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, sub)
+			s.mu.Unlock()
+			close(sub.gone)
+		})
+	}
+}
+
+// Done is closed once the session has ended and every tick was handed to
+// the clients' channels.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Status reports the session's progress.
+func (s *Session) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Status{
+		ID:        s.ID,
+		Source:    s.spec.Source,
+		Pace:      s.pace.String(),
+		State:     s.state,
+		Ticks:     s.ticks,
+		Position:  s.position,
+		Clients:   len(s.subs),
+		CreatedAt: s.created,
+		EndedAt:   s.ended,
+	}
+	if s.err != nil {
+		st.Error = s.err.Error()
+	}
+	return st
+}
+
+func (s *Session) run() {
+	defer close(s.done)
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case <-s.start:
+	case <-timer.C:
+		s.src.Close()
+		s.finish(fmt.Errorf("no client joined within %s", s.timeout))
+		return
+	case <-s.ctx.Done():
+		s.src.Close()
+		s.finish(s.ctx.Err())
+		return
+	}
+	s.mu.Lock()
+	s.state = StatePlaying
+	s.mu.Unlock()
+
+	data := make(chan models.MarketData, 1024)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- NewReplayer(s.src, s.pace).Run(s.ctx, data)
+		close(data)
+	}()
+	for m := range data {
+		s.fanOut(m)
+	}
+	s.finish(<-errc)
+}
+
+func (s *Session) fanOut(m models.MarketData) {
+	s.mu.Lock()
+	s.ticks++
+	s.position = m.Timestamp
+	subs := make([]*subscriber, 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.ch <- m:
+		case <-sub.gone:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Session) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended = time.Now()
+	switch {
+	case err == nil:
+		s.state = StateDone
+	case errors.Is(err, context.Canceled):
+		s.state = StateStopped
+	default:
+		s.state, s.err = StateFailed, err
+		s.logger.Warn(fmt.Sprintf("Replay %s failed after %d ticks: %v", s.ID, s.ticks, err))
+	}
+}
+
+// finishedRetention is how long a session stays listed after it ends.
+const finishedRetention = 10 * time.Minute
+
+// Manager keeps the replay sessions. Sessions stay listed for
+// finishedRetention after they end, or until they are stopped.
+type Manager struct {
+	store       *storage.Store
+	dir         string
+	maxSessions int
+	joinTimeout time.Duration
+	logger      *logrus.Logger
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewManager builds the manager. store (optional) enables Postgres sources;
+// file sources are read from dir. At most maxSessions sessions may be
+// waiting or playing at once, and a session fails when no client joins it
+// within joinTimeout.
+func NewManager(store *storage.Store, dir string, maxSessions int, joinTimeout time.Duration) *Manager {
+	return &Manager{
+		store:       store,
+		dir:         dir,
+		maxSessions: maxSessions,
+		joinTimeout: joinTimeout,
+		logger:      logger.GetLogger(),
+		sessions:    make(map[string]*Session),
+	}
+}
+
+// Start creates a session for spec; it begins playing when a client joins
+// and fails if none joins within the manager's join timeout.
+func (m *Manager) Start(spec Spec) (*Session, error) {
+	pace, err := ParsePace(spec.Pace)
+	if err != nil {
+		return nil, err
+	}
+	src, err := m.source(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if spec.ID == "" {
+		spec.ID = newID()
+	}
+	if _, ok := m.sessions[spec.ID]; ok {
+		src.Close()
+		return nil, fmt.Errorf("replay %q already exists", spec.ID)
+	}
+	active := 0
+	for _, s := range m.sessions {
+		select {
+		case <-s.done:
+		default:
+			active++
+		}
+	}
+	if active >= m.maxSessions {
+		src.Close()
+		return nil, fmt.Errorf("too many replays running (max %d)", m.maxSessions)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Session{
+		ID:      spec.ID,
+		spec:    spec,
+		pace:    pace,
+		src:     src,
+		created: time.Now(),
+		timeout: m.joinTimeout,
+		logger:  m.logger,
+		ctx:     ctx,
+		cancel:  cancel,
+		start:   make(chan struct{}),
+		done:    make(chan struct{}),
+		state:   StateWaiting,
+		subs:    make(map[*subscriber]struct{}),
+	}
+	m.sessions[s.ID] = s
+	go s.run()
+	m.logger.Info(fmt.Sprintf("Replay %s created (%s, pace %s)", s.ID, spec.Source, pace))
+	return s, nil
+}
+
+func (m *Manager) source(spec Spec) (Source, error) {
+	switch spec.Source {
+	case "postgres":
+		if m.store == nil {
+			return nil, fmt.Errorf("postgres replay is unavailable")
+		}
+		if len(spec.Symbols) == 0 {
+			return nil, fmt.Errorf("symbols is required")
+		}
+		r := storage.Range{Symbols: spec.Symbols}
+		var err error
+		if r.From, err = utils.ParseMillis(spec.From); err != nil {
+			return nil, fmt.Errorf("invalid from %q", spec.From)
+		}
+		if r.To, err = utils.ParseMillis(spec.To); err != nil {
+			return nil, fmt.Errorf("invalid to %q", spec.To)
+		}
+		return NewStoreSource(m.store, r), nil
+	case "file":
+		if !filepath.IsLocal(spec.File) {
+			return nil, fmt.Errorf("file must be a path inside the replay directory")
+		}
+		return NewFileSource(filepath.Join(m.dir, spec.File))
+	}
+	return nil, fmt.Errorf("invalid source %q (want postgres or file)", spec.Source)
+}
+
+// Get returns the session with id.
+func (m *Manager) Get(id string) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	return s, ok
+}
+
+// Stop ends the session with id and forgets it; its clients are told the
+// session ended.
+func (m *Manager) Stop(id string) bool {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	if ok {
+		s.cancel()
+	}
+	return ok
+}
+
+// List reports every session, oldest first.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	list := make([]Status, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, s.Status())
+	}
+	m.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Run evicts sessions that ended more than finishedRetention ago and stops
+// every session when ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			for id, s := range m.sessions {
+				s.cancel()
+				delete(m.sessions, id)
+			}
+			return
+		case now := <-ticker.C:
+			m.evict(now.Add(-finishedRetention))
+		}
+	}
+}
+
+// evict forgets sessions that ended before cutoff.
+func (m *Manager) evict(cutoff time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		s.mu.Lock()
+		ended := s.ended
+		s.mu.Unlock()
+		if !ended.IsZero() && ended.Before(cutoff) {
+			s.cancel()
+			delete(m.sessions, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,54 @@
+package rollover
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+var futurePattern = regexp.MustCompile(`^(.+?)(\d{2})(JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)FUT$`)
+
+// Contract is a month-coded future such as BANKNIFTY25DECFUT.
+type Contract struct {
+	Root  string
+	Year  int
+	Month time.Month
+}
+
+// ParseFuture parses <ROOT><YY><MON>FUT tradingsymbols.
+func ParseFuture(symbol string) (Contract, bool) {
+	m := futurePattern.FindStringSubmatch(symbol)
+	if m == nil {
+		return Contract{}, false
+	}
+	yy, _ := strconv.Atoi(m[2])
+	return Contract{
+		Root:  m[1],
+		Year:  2000 + yy,
+		Month: time.Month(indexOf(months, m[3]) + 1),
+	}, true
+}
+
+func (c Contract) Symbol() string {
+	return fmt.Sprintf("%s%02d%sFUT", c.Root, c.Year%100, months[c.Month-1])
+}
+
+// Next returns the following month's contract.
+func (c Contract) Next() Contract {
+	if c.Month == time.December {
+		return Contract{Root: c.Root, Year: c.Year + 1, Month: time.January}
+	}
+	return Contract{Root: c.Root, Year: c.Year, Month: c.Month + 1}
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
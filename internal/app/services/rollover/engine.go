@@ -0,0 +1,400 @@
+package rollover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/instruments"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	RuleDate = "date"
+	RuleOI   = "oi"
+)
+
+// Subscriber manages upstream subscriptions, e.g. websocket.Registry.
+type Subscriber interface {
+	Subscriptions() map[string][]string
+	Subscribe(feed string, symbols ...string) error
+	Unsubscribe(feed string, symbols ...string) error
+}
+
+// Recorder persists roll events and returns the latest one per root, so a
+// restart resumes from the last roll.
+type Recorder interface {
+	RecordRoll(ctx context.Context, ev models.RollEvent) error
+	LatestRolls(ctx context.Context) ([]models.RollEvent, error)
+}
+
+type Options struct {
+	// Rule is "date" (roll RollDays before expiry) or "oi" (roll once the
+	// next contract's open interest overtakes the active one, and on expiry
+	// day at the latest).
+	Rule string
+	// PreSubscribeDays subscribes the next contract this many days before
+	// the active contract expires.
+	PreSubscribeDays int
+	RollDays         int
+	AliasSuffix      string
+	Interval         time.Duration
+}
+
+type chain struct {
+	feed     string
+	exchange string
+	active   string
+	next     string
+	// restored is set while active comes from a roll event recorded before
+	// the restart and has not been evaluated yet.
+	restored bool
+}
+
+// Engine keeps month-coded futures subscriptions rolling ahead of expiry and
+// publishes a continuous alias (e.g. BANKNIFTY-I) for the active contract.
+type Engine struct {
+	opts     Options
+	catalog  *instruments.Catalog
+	subs     Subscriber
+	recorder Recorder
+	logger   *logrus.Logger
+
+	mu      sync.RWMutex
+	chains  map[string]*chain // root -> chain
+	aliases map[string]string // active symbol -> alias
+	oi      map[string]models.Decimal
+}
+
+func New(opts Options, catalog *instruments.Catalog, subs Subscriber, recorder Recorder) *Engine {
+	if opts.AliasSuffix == "" {
+		opts.AliasSuffix = "-I"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	return &Engine{
+		opts:     opts,
+		catalog:  catalog,
+		subs:     subs,
+		recorder: recorder,
+		logger:   logger.GetLogger(),
+		chains:   make(map[string]*chain),
+		aliases:  make(map[string]string),
+		oi:       make(map[string]models.Decimal),
+	}
+}
+
+// Start restores the active contracts from the recorded roll events, then
+// evaluates rollovers immediately and every Interval.
+func (e *Engine) Start(ctx context.Context) {
+	e.restore(ctx)
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+	for {
+		e.Evaluate(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run forwards ticks from in to out, tracking open interest and emitting a
+// copy of every active-contract tick under its continuous alias. Copies are
+// flagged Alias so the store skips them.
+func (e *Engine) Run(ctx context.Context, in <-chan models.MarketData, out chan<- models.MarketData) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-in:
+			if oi, ok := openInterest(m); ok {
+				e.mu.Lock()
+				e.oi[m.Name] = oi
+				e.mu.Unlock()
+			}
+			if !send(ctx, out, m) {
+				return
+			}
+
+			e.mu.RLock()
+			alias, ok := e.aliases[m.Name]
+			e.mu.RUnlock()
+			if ok {
+				aliased := m
+				aliased.Name = alias
+				aliased.Alias = true
+				if !send(ctx, out, aliased) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// restore seeds each root's chain with the contract it last rolled to.
+func (e *Engine) restore(ctx context.Context) {
+	if e.recorder == nil {
+		return
+	}
+	events, err := e.recorder.LatestRolls(ctx)
+	if err != nil {
+		e.logger.Error(fmt.Sprintf("Rollover: failed to load roll events: %v", err))
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, ev := range events {
+		if _, ok := e.chains[ev.Root]; ok {
+			continue
+		}
+		e.chains[ev.Root] = &chain{active: ev.To, restored: true}
+		e.aliases[ev.To] = ev.Root + e.opts.AliasSuffix
+		e.logger.Info(fmt.Sprintf("Rollover: restored %s -> %s from roll at %s", ev.Root+e.opts.AliasSuffix, ev.To, ev.RolledAt.Format(time.RFC3339)))
+	}
+}
+
+// Aliases returns alias -> active contract.
+func (e *Engine) Aliases() map[string]string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	out := make(map[string]string, len(e.aliases))
+	for sym, alias := range e.aliases {
+		out[alias] = sym
+	}
+	return out
+}
+
+func send(ctx context.Context, out chan<- models.MarketData, m models.MarketData) bool {
+	select {
+	case out <- m:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func openInterest(m models.MarketData) (models.Decimal, bool) {
+	p := m.Payload()
+	for _, k := range []string{"oi", "open_interest", "openInterest"} {
+		if v, ok := p[k]; ok {
+			return models.DecimalFrom(v)
+		}
+	}
+	return models.Decimal{}, false
+}
+
+type contractRef struct {
+	Contract
+	symbol   string
+	feed     string
+	exchange string
+	expiry   time.Time
+}
+
+// Evaluate runs one rollover pass at now.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) {
+	groups := make(map[string][]contractRef)
+	for feed, symbols := range e.subs.Subscriptions() {
+		for _, sym := range symbols {
+			c, ok := ParseFuture(sym)
+			if !ok {
+				continue
+			}
+			exch, _ := e.catalog.Exchange(sym)
+			expiry, err := e.expiry(c, sym, exch)
+			if err != nil {
+				e.logger.Warn(fmt.Sprintf("Rollover: no expiry for %s: %v", sym, err))
+				continue
+			}
+			groups[c.Root] = append(groups[c.Root], contractRef{Contract: c, symbol: sym, feed: feed, exchange: exch, expiry: expiry})
+		}
+	}
+
+	for root, refs := range groups {
+		sort.Slice(refs, func(i, j int) bool { return refs[i].expiry.Before(refs[j].expiry) })
+		e.evaluateRoot(ctx, root, refs, now)
+	}
+}
+
+func (e *Engine) evaluateRoot(ctx context.Context, root string, refs []contractRef, now time.Time) {
+	e.mu.Lock()
+	ch, known := e.chains[root]
+	if !known {
+		ch = &chain{}
+		e.chains[root] = ch
+	}
+	e.mu.Unlock()
+
+	bySymbol := make(map[string]contractRef, len(refs))
+	for _, r := range refs {
+		bySymbol[r.symbol] = r
+	}
+
+	// The contract restored from the last roll may be missing from the
+	// configured subscriptions; subscribe it rather than rolling again
+	if ch.restored {
+		ch.restored = false
+		if _, ok := bySymbol[ch.active]; !ok && e.resubscribe(ctx, refs[0], ch.active, now) {
+			return
+		}
+	}
+
+	// Active contract: keep the current one while it is live, otherwise take
+	// the nearest unexpired contract.
+	active, ok := bySymbol[ch.active]
+	if !ok || expired(active, now) {
+		for _, r := range refs {
+			if !expired(r, now) {
+				active, ok = r, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return
+	}
+	ch.feed, ch.exchange = active.feed, active.exchange
+	e.setActive(root, ch, active.symbol)
+
+	// Subscribe the next contract ahead of expiry
+	nextC := active.Next()
+	next, subscribed := bySymbol[nextC.Symbol()]
+	if !subscribed && now.After(active.expiry.AddDate(0, 0, -e.opts.PreSubscribeDays)) {
+		e.register(ctx, active, nextC)
+		if err := e.subs.Subscribe(active.feed, nextC.Symbol()); err != nil {
+			e.logger.Error(fmt.Sprintf("Rollover: failed to subscribe %s: %v", nextC.Symbol(), err))
+		} else {
+			e.logger.Info(fmt.Sprintf("Rollover: subscribed next contract %s for %s", nextC.Symbol(), root))
+		}
+		return
+	}
+	ch.next = next.symbol
+
+	if subscribed {
+		if reason, roll := e.shouldRoll(active, next, now); roll {
+			e.roll(ctx, root, ch, active.symbol, next.symbol, reason, now)
+		}
+	}
+
+	// Drop contracts that have expired and are no longer active
+	for _, r := range refs {
+		if expired(r, now) && r.symbol != ch.active {
+			if err := e.subs.Unsubscribe(r.feed, r.symbol); err == nil {
+				e.logger.Info(fmt.Sprintf("Rollover: unsubscribed expired contract %s", r.symbol))
+			}
+		}
+	}
+}
+
+func (e *Engine) shouldRoll(active, next contractRef, now time.Time) (string, bool) {
+	rollAt := active.expiry.AddDate(0, 0, -e.opts.RollDays)
+	if e.opts.Rule == RuleOI {
+		e.mu.RLock()
+		cur, okC := e.oi[active.symbol]
+		nxt, okN := e.oi[next.symbol]
+		e.mu.RUnlock()
+		if okC && okN && nxt.Sign() > 0 && nxt.Cmp(cur) >= 0 {
+			return fmt.Sprintf("open interest %s >= %s", nxt, cur), true
+		}
+		// OI never crossed: roll on expiry day at the latest
+		if !now.Before(active.expiry) {
+			return fmt.Sprintf("open interest did not cross by expiry %s", active.expiry.Format("2006-01-02")), true
+		}
+		return "", false
+	}
+	if !now.Before(rollAt) {
+		return fmt.Sprintf("%d days before expiry %s (roll date %s)", e.opts.RollDays, active.expiry.Format("2006-01-02"), rollAt.Format("2006-01-02")), true
+	}
+	return "", false
+}
+
+func (e *Engine) setActive(root string, ch *chain, symbol string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if ch.active == symbol {
+		return
+	}
+	delete(e.aliases, ch.active)
+	ch.active = symbol
+	e.aliases[symbol] = root + e.opts.AliasSuffix
+}
+
+func (e *Engine) roll(ctx context.Context, root string, ch *chain, from, to, reason string, now time.Time) {
+	e.setActive(root, ch, to)
+	ev := models.RollEvent{
+		Root:     root,
+		Alias:    root + e.opts.AliasSuffix,
+		From:     from,
+		To:       to,
+		Rule:     e.opts.Rule,
+		Reason:   reason,
+		RolledAt: now,
+	}
+	e.logger.Info(fmt.Sprintf("Rollover: %s rolled %s -> %s (%s)", ev.Alias, from, to, reason))
+	metrics.Rollovers.WithLabelValues(root).Inc()
+	if e.recorder != nil {
+		if err := e.recorder.RecordRoll(ctx, ev); err != nil {
+			e.logger.Error(fmt.Sprintf("Failed to record roll event for %s: %v", root, err))
+		}
+	}
+}
+
+// resubscribe subscribes symbol on ref's feed when it is an unexpired
+// contract of the same root.
+func (e *Engine) resubscribe(ctx context.Context, ref contractRef, symbol string, now time.Time) bool {
+	c, ok := ParseFuture(symbol)
+	if !ok || c.Root != ref.Root {
+		return false
+	}
+	expiry, err := e.expiry(c, symbol, ref.exchange)
+	if err != nil || expired(contractRef{expiry: expiry}, now) {
+		return false
+	}
+	e.register(ctx, ref, c)
+	if err := e.subs.Subscribe(ref.feed, symbol); err != nil {
+		e.logger.Error(fmt.Sprintf("Rollover: failed to resubscribe %s: %v", symbol, err))
+		return false
+	}
+	e.logger.Info(fmt.Sprintf("Rollover: resubscribed restored contract %s for %s", symbol, c.Root))
+	return true
+}
+
+// register adds the next contract to the instrument master, copying the
+// active contract's attributes, so its exchange resolves once it ticks.
+func (e *Engine) register(ctx context.Context, active contractRef, next Contract) {
+	if _, ok := e.catalog.Lookup(next.Symbol()); ok {
+		return
+	}
+	in, ok := e.catalog.Lookup(active.symbol)
+	if !ok {
+		return
+	}
+	expiry, err := e.expiry(next, next.Symbol(), active.exchange)
+	if err != nil {
+		return
+	}
+	in.Symbol = next.Symbol()
+	in.Expiry = &expiry
+	in.Active = true
+	if err := e.catalog.Upsert(ctx, in); err != nil {
+		e.logger.Warn(fmt.Sprintf("Rollover: failed to register %s: %v", in.Symbol, err))
+	}
+}
+
+func (e *Engine) expiry(c Contract, symbol, exchange string) (time.Time, error) {
+	return e.catalog.ContractExpiry(symbol, exchange, c.Year, c.Month)
+}
+
+// expired reports whether the contract's expiry day has passed.
+func expired(r contractRef, now time.Time) bool {
+	return !now.Before(r.expiry.AddDate(0, 0, 1))
+}
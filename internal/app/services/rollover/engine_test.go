@@ -0,0 +1,255 @@
+package rollover
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/instruments"
+)
+
+const (
+	octFut = "NIFTY26OCTFUT"
+	novFut = "NIFTY26NOVFUT"
+)
+
+type fakeSubscriber struct {
+	mu   sync.Mutex
+	subs map[string][]string
+}
+
+func (s *fakeSubscriber) Subscriptions() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string][]string, len(s.subs))
+	for feed, syms := range s.subs {
+		out[feed] = slices.Clone(syms)
+	}
+	return out
+}
+
+func (s *fakeSubscriber) Subscribe(feed string, symbols ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sym := range symbols {
+		if !slices.Contains(s.subs[feed], sym) {
+			s.subs[feed] = append(s.subs[feed], sym)
+		}
+	}
+	return nil
+}
+
+func (s *fakeSubscriber) Unsubscribe(feed string, symbols ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[feed] = slices.DeleteFunc(s.subs[feed], func(sym string) bool { return slices.Contains(symbols, sym) })
+	return nil
+}
+
+func (s *fakeSubscriber) has(feed, symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Contains(s.subs[feed], symbol)
+}
+
+type fakeRecorder struct {
+	latest []models.RollEvent
+	rolls  []models.RollEvent
+}
+
+func (r *fakeRecorder) RecordRoll(_ context.Context, ev models.RollEvent) error {
+	r.rolls = append(r.rolls, ev)
+	return nil
+}
+
+func (r *fakeRecorder) LatestRolls(context.Context) ([]models.RollEvent, error) {
+	return r.latest, nil
+}
+
+func ist(y int, m time.Month, d, hour int) time.Time {
+	return time.Date(y, m, d, hour, 0, 0, 0, instruments.Location)
+}
+
+// newTestEngine returns an engine over the October and November NIFTY
+// futures, expiring on 27 Oct and 24 Nov 2026, with subscribed on feed "f".
+func newTestEngine(rule string, rec *fakeRecorder, subscribed ...string) (*Engine, *fakeSubscriber) {
+	octExpiry, novExpiry := ist(2026, time.October, 27, 0), ist(2026, time.November, 24, 0)
+	catalog := instruments.NewCatalog(nil)
+	catalog.Replace([]models.Instrument{
+		{Symbol: octFut, Exchange: "nse", Expiry: &octExpiry, Active: true},
+		{Symbol: novFut, Exchange: "nse", Expiry: &novExpiry, Active: true},
+	})
+	subs := &fakeSubscriber{subs: map[string][]string{"f": subscribed}}
+	e := New(Options{Rule: rule, PreSubscribeDays: 5, RollDays: 1}, catalog, subs, rec)
+	return e, subs
+}
+
+func wantAlias(t *testing.T, e *Engine, want string) {
+	t.Helper()
+	if got := e.Aliases()["NIFTY-I"]; got != want {
+		t.Errorf("NIFTY-I -> %q, want %q", got, want)
+	}
+}
+
+func TestDateRule(t *testing.T) {
+	ctx := context.Background()
+	rec := &fakeRecorder{}
+	e, subs := newTestEngine(RuleDate, rec, octFut)
+
+	e.Evaluate(ctx, ist(2026, time.October, 20, 10))
+	wantAlias(t, e, octFut)
+	if subs.has("f", novFut) {
+		t.Errorf("%s subscribed before the pre-subscribe window", novFut)
+	}
+
+	e.Evaluate(ctx, ist(2026, time.October, 23, 10))
+	if !subs.has("f", novFut) {
+		t.Fatalf("%s not subscribed inside the pre-subscribe window", novFut)
+	}
+	e.Evaluate(ctx, ist(2026, time.October, 23, 11))
+	wantAlias(t, e, octFut)
+	if len(rec.rolls) != 0 {
+		t.Fatalf("rolled before the roll date: %+v", rec.rolls)
+	}
+
+	e.Evaluate(ctx, ist(2026, time.October, 26, 9))
+	wantAlias(t, e, novFut)
+	if len(rec.rolls) != 1 {
+		t.Fatalf("got %d roll events, want 1", len(rec.rolls))
+	}
+	ev := rec.rolls[0]
+	if ev.From != octFut || ev.To != novFut || ev.Rule != RuleDate || ev.Alias != "NIFTY-I" {
+		t.Errorf("roll event = %+v", ev)
+	}
+	if !strings.Contains(ev.Reason, "roll date 2026-10-26") {
+		t.Errorf("reason %q does not name the roll date", ev.Reason)
+	}
+
+	// The expired contract is dropped the day after expiry
+	e.Evaluate(ctx, ist(2026, time.October, 28, 9))
+	if subs.has("f", octFut) {
+		t.Errorf("expired %s still subscribed", octFut)
+	}
+	if len(rec.rolls) != 1 {
+		t.Errorf("rolled again: %+v", rec.rolls[1:])
+	}
+}
+
+func tickWithOI(symbol string, oi int) models.MarketData {
+	return models.MarketData{Name: symbol, Timestamp: 1, Data: map[string]interface{}{"oi": oi}}
+}
+
+// feed passes ticks through Run and returns what it emitted.
+func feed(t *testing.T, e *Engine, ticks ...models.MarketData) []models.MarketData {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan models.MarketData)
+	out := make(chan models.MarketData, 2*len(ticks))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx, in, out)
+	}()
+	for _, m := range ticks {
+		in <- m
+	}
+	// An unbuffered send only returns once Run took the tick; one more
+	// makes sure the previous one was fully forwarded
+	in <- models.MarketData{Name: "SENTINEL"}
+	cancel()
+	<-done
+	close(out)
+	var got []models.MarketData
+	for m := range out {
+		if m.Name != "SENTINEL" {
+			got = append(got, m)
+		}
+	}
+	return got
+}
+
+func TestOIRule(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rolls when open interest crosses", func(t *testing.T) {
+		rec := &fakeRecorder{}
+		e, _ := newTestEngine(RuleOI, rec, octFut, novFut)
+		feed(t, e, tickWithOI(octFut, 1000), tickWithOI(novFut, 400))
+
+		// The date rule would roll here; the OI rule waits for the crossover
+		e.Evaluate(ctx, ist(2026, time.October, 26, 10))
+		wantAlias(t, e, octFut)
+
+		feed(t, e, tickWithOI(novFut, 1200))
+		e.Evaluate(ctx, ist(2026, time.October, 26, 11))
+		wantAlias(t, e, novFut)
+		if len(rec.rolls) != 1 || !strings.HasPrefix(rec.rolls[0].Reason, "open interest 1200 >= 1000") {
+			t.Errorf("roll events = %+v", rec.rolls)
+		}
+	})
+
+	t.Run("falls back to expiry day", func(t *testing.T) {
+		rec := &fakeRecorder{}
+		e, _ := newTestEngine(RuleOI, rec, octFut, novFut)
+		feed(t, e, tickWithOI(octFut, 1000), tickWithOI(novFut, 400))
+
+		e.Evaluate(ctx, ist(2026, time.October, 26, 23))
+		wantAlias(t, e, octFut)
+
+		e.Evaluate(ctx, ist(2026, time.October, 27, 0))
+		wantAlias(t, e, novFut)
+		if len(rec.rolls) != 1 {
+			t.Fatalf("got %d roll events, want 1", len(rec.rolls))
+		}
+		if reason := rec.rolls[0].Reason; reason != "open interest did not cross by expiry 2026-10-27" {
+			t.Errorf("reason = %q", reason)
+		}
+	})
+}
+
+func TestRunPublishesAlias(t *testing.T) {
+	e, _ := newTestEngine(RuleDate, &fakeRecorder{}, octFut, novFut)
+	e.Evaluate(context.Background(), ist(2026, time.October, 20, 10))
+
+	got := feed(t, e, models.MarketData{Name: octFut, Timestamp: 5}, models.MarketData{Name: novFut, Timestamp: 6})
+	if len(got) != 3 {
+		t.Fatalf("got %d ticks, want the two ticks and one alias copy: %+v", len(got), got)
+	}
+	if got[0].Name != octFut || got[0].Alias {
+		t.Errorf("first tick = %s (alias %v), want the contract itself", got[0].Name, got[0].Alias)
+	}
+	if got[1].Name != "NIFTY-I" || !got[1].Alias || got[1].Timestamp != 5 {
+		t.Errorf("alias copy = %+v", got[1])
+	}
+	if got[2].Name != novFut || got[2].Alias {
+		t.Errorf("inactive contract tick = %s (alias %v)", got[2].Name, got[2].Alias)
+	}
+}
+
+func TestRestoreFromRollEvents(t *testing.T) {
+	ctx := context.Background()
+	rec := &fakeRecorder{latest: []models.RollEvent{{
+		Root: "NIFTY", Alias: "NIFTY-I", From: octFut, To: novFut, Rule: RuleDate,
+		RolledAt: ist(2026, time.October, 19, 10),
+	}}}
+	// The configured subscriptions predate the roll
+	e, subs := newTestEngine(RuleDate, rec, octFut)
+	e.restore(ctx)
+	wantAlias(t, e, novFut)
+
+	e.Evaluate(ctx, ist(2026, time.October, 20, 10))
+	if !subs.has("f", novFut) {
+		t.Errorf("restored contract %s not resubscribed", novFut)
+	}
+	wantAlias(t, e, novFut)
+
+	e.Evaluate(ctx, ist(2026, time.October, 20, 11))
+	wantAlias(t, e, novFut)
+	if len(rec.rolls) != 0 {
+		t.Errorf("restore recorded new rolls: %+v", rec.rolls)
+	}
+}
@@ -0,0 +1,14 @@
+package rollover
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NewAliasHandler serves GET /rollover: continuous alias -> active contract.
+func NewAliasHandler(e *Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(e.Aliases())
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+)
+
+// Postgres accepts at most 65535 bind parameters per statement.
+const maxCandleRows = 65535 / 11
+
+// UpsertCandles writes bars keyed by (symbol, interval, start). A bar that is
+// already stored, e.g. a partial one flushed before a restart, is merged:
+// the earlier open is kept, high and low widen, and close, volume and tick
+// count take in the new part.
+func (s *Store) UpsertCandles(ctx context.Context, candles []models.Candle) error {
+	for len(candles) > 0 {
+		chunk := candles[:min(len(candles), maxCandleRows)]
+		candles = candles[len(chunk):]
+
+		var query strings.Builder
+		query.WriteString(`INSERT INTO ` + constants.BARS_TABLE_NAME + ` (symbol, exchange, interval, start_ts, end_ts, open, high, low, close, volume, ticks) VALUES `)
+		args := make([]any, 0, len(chunk)*11)
+		for i, c := range chunk {
+			if i > 0 {
+				query.WriteByte(',')
+			}
+			n := len(args)
+			fmt.Fprintf(&query, "($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11)
+			var volume any
+			if c.Volume != nil {
+				volume = *c.Volume
+			}
+			args = append(args, c.Symbol, c.Exchange, c.Interval, c.Start, c.End, c.Open, c.High, c.Low, c.Close, volume, c.Ticks)
+		}
+		query.WriteString(` ON CONFLICT (symbol, interval, start_ts) DO UPDATE SET
+			high = GREATEST(` + constants.BARS_TABLE_NAME + `.high, EXCLUDED.high),
+			low = LEAST(` + constants.BARS_TABLE_NAME + `.low, EXCLUDED.low),
+			close = EXCLUDED.close,
+			volume = CASE WHEN EXCLUDED.volume IS NULL THEN ` + constants.BARS_TABLE_NAME + `.volume
+				ELSE COALESCE(` + constants.BARS_TABLE_NAME + `.volume, 0) + EXCLUDED.volume END,
+			ticks = ` + constants.BARS_TABLE_NAME + `.ticks + EXCLUDED.ticks`)
+
+		if _, err := s.db.ExecContext(ctx, query.String(), args...); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to upsert %d bars: %v", len(chunk), err))
+			return err
+		}
+	}
+	return nil
+}
+
+// BarsKey is the Redis hash holding the bars of symbol: field <interval> is
+// the bar in progress and <interval>:closed the last closed one.
+func BarsKey(symbol string) string {
+	return "bars:" + symbol
+}
+
+// PutCandles caches the given bars under BarsKey.
+func (c *CacheService) PutCandles(ctx context.Context, candles []models.Candle, ttl time.Duration) error {
+	pipe := c.Client.Pipeline()
+	for _, candle := range candles {
+		value, err := json.Marshal(candle)
+		if err != nil {
+			continue
+		}
+		field := candle.Interval
+		if candle.Final {
+			field += ":closed"
+		}
+		key := BarsKey(candle.Symbol)
+		pipe.HSet(ctx, key, field, value)
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetCandles returns the cached bar in progress and the last closed bar of
+// symbol for interval; either is nil when not cached.
+func (c *CacheService) GetCandles(ctx context.Context, symbol, interval string) (current, closed *models.Candle, err error) {
+	values, err := c.Client.HMGet(ctx, BarsKey(symbol), interval, interval+":closed").Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	decode := func(v any) *models.Candle {
+		s, ok := v.(string)
+		if !ok {
+			return nil
+		}
+		var candle models.Candle
+		if json.Unmarshal([]byte(s), &candle) != nil {
+			return nil
+		}
+		return &candle
+	}
+	return decode(values[0]), decode(values[1]), nil
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// InsertMode selects how InsertBatch writes ticks.
+type InsertMode string
+
+const (
+	// InsertRow executes a prepared single-row INSERT per tick.
+	InsertRow InsertMode = "row"
+	// InsertValues sends multi-row INSERT ... VALUES statements.
+	InsertValues InsertMode = "values"
+	// InsertCopy streams the batch with the COPY protocol.
+	InsertCopy InsertMode = "copy"
+)
+
+// ParseInsertMode validates a STORE_INSERT_MODE value.
+func ParseInsertMode(s string) (InsertMode, error) {
+	switch m := InsertMode(strings.ToLower(s)); m {
+	case InsertRow, InsertValues, InsertCopy:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown insert mode %q (want row, values or copy)", s)
+}
+
+// SetInsertMode switches the InsertBatch strategy.
+func (s *Store) SetInsertMode(mode InsertMode) {
+	s.insertMode = mode
+}
+
+var marketDataFields = []string{"name", "timestamp", "exchange", "feed", "kind", "price", "bid", "ask", "tick", "data"}
+
+var marketDataColumns = strings.Join(marketDataFields, ", ")
+
+// Scratch returns a copy of the store whose InsertBatch writes to table,
+// created empty with the market data table's columns and indexes. The bench
+// command uses it to measure insert modes without touching real data.
+func (s *Store) Scratch(ctx context.Context, table string) (*Store, error) {
+	if _, err := s.db.ExecContext(ctx, `DROP TABLE IF EXISTS `+table); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE `+table+` (LIKE `+s.table+` INCLUDING ALL)`); err != nil {
+		return nil, err
+	}
+	scratch := *s
+	scratch.table = table
+	return &scratch, nil
+}
+
+// DropScratch removes a table created by Scratch.
+func (s *Store) DropScratch(ctx context.Context) error {
+	if s.table == constants.MARKET_DATA_TABLE_NAME {
+		return fmt.Errorf("refusing to drop %s", s.table)
+	}
+	_, err := s.db.ExecContext(ctx, `DROP TABLE IF EXISTS `+s.table)
+	return err
+}
+
+// Postgres accepts at most 65535 bind parameters per statement.
+const maxValuesRows = 65535 / 10
+
+func rowValues(record models.MarketData) []any {
+	dataBytes, _ := json.Marshal(record.Data)
+	var tick any // NULL for raw records
+	if typed := record.Typed(); typed != nil {
+		tick = typed
+	}
+	price, bid, ask := headline(record)
+	return []any{record.Name, record.Timestamp, record.Exchange, record.Feed, string(record.Kind), price, bid, ask, tick, dataBytes}
+}
+
+// insertValues writes rows with as few multi-row INSERTs as the parameter
+// limit allows.
+func (s *Store) insertValues(ctx context.Context, tx *sql.Tx, rows []models.MarketData) (int64, error) {
+	var inserted int64
+	for len(rows) > 0 {
+		chunk := rows[:min(len(rows), maxValuesRows)]
+		rows = rows[len(chunk):]
+
+		var query strings.Builder
+		query.WriteString(`INSERT INTO ` + s.table + ` (` + marketDataColumns + `) VALUES `)
+		args := make([]any, 0, len(chunk)*len(marketDataFields))
+		for i, record := range chunk {
+			if i > 0 {
+				query.WriteByte(',')
+			}
+			query.WriteByte('(')
+			for j := range marketDataFields {
+				if j > 0 {
+					query.WriteByte(',')
+				}
+				query.WriteString("$" + strconv.Itoa(len(args)+j+1))
+			}
+			query.WriteByte(')')
+			args = append(args, rowValues(record)...)
+		}
+		if s.uniqueTicks {
+			query.WriteString(` ON CONFLICT DO NOTHING`)
+		}
+
+		res, err := tx.ExecContext(ctx, query.String(), args...)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to insert %d rows into %s: %v", len(chunk), s.table, err))
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		inserted += n
+	}
+	return inserted, nil
+}
+
+// copyRows streams rows with COPY. COPY cannot skip conflicts, so with unique
+// ticks the batch is copied into a transaction-scoped staging table first and
+// moved across with INSERT ... ON CONFLICT DO NOTHING.
+func (s *Store) copyRows(ctx context.Context, tx *sql.Tx, rows []models.MarketData) (int64, error) {
+	target := s.table
+	if s.uniqueTicks {
+		target = s.table + "_stage"
+		// The staging table outlives a savepoint that succeeded, so a later
+		// chunk of a bisected batch reuses it
+		_, err := tx.ExecContext(ctx, `CREATE TEMP TABLE IF NOT EXISTS `+target+` (
+				name VARCHAR(255),
+				timestamp BIGINT,
+				exchange VARCHAR(100),
+				feed VARCHAR(100),
+				kind VARCHAR(16),
+				price NUMERIC,
+				bid NUMERIC,
+				ask NUMERIC,
+				tick JSONB,
+				data JSONB
+			) ON COMMIT DROP`)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `TRUNCATE `+target)
+		}
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to prepare staging table %s: %v", target, err))
+			return 0, err
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(target, marketDataFields...))
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to start COPY into %s: %v", target, err))
+		return 0, err
+	}
+	for _, record := range rows {
+		values := rowValues(record)
+		// COPY sends []byte as bytea; the JSONB columns need text
+		if tick, ok := values[8].([]byte); ok {
+			values[8] = string(tick)
+		}
+		values[9] = string(values[9].([]byte))
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			stmt.Close()
+			s.logger.Error(fmt.Sprintf("Failed to copy %s: %v", record.Name, err))
+			return 0, err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		s.logger.Error(fmt.Sprintf("Failed to flush COPY into %s: %v", target, err))
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+	if !s.uniqueTicks {
+		return int64(len(rows)), nil
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO `+s.table+` (`+marketDataColumns+`)
+		SELECT `+marketDataColumns+` FROM `+target+` ON CONFLICT DO NOTHING`)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to move staged rows into %s: %v", s.table, err))
+		return 0, err
+	}
+	return res.RowsAffected()
+}
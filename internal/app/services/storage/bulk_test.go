@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+// BenchmarkInsertBatch writes batches of synthetic ticks into a scratch
+// table with each insert mode. It needs a disposable database:
+//
+//	TEST_DATABASE_URL=postgres://localhost/ws_test?sslmode=disable go test -run - -bench InsertBatch ./internal/app/services/storage
+func BenchmarkInsertBatch(b *testing.B) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL not set")
+	}
+	store, err := NewPostgres(url, false, PartitionOptions{})
+	if err != nil {
+		b.Fatalf("connect: %v", err)
+	}
+	defer store.Close()
+
+	const batchSize = 500
+	ctx := context.Background()
+	batch := benchTicks(batchSize)
+
+	for _, mode := range []InsertMode{InsertRow, InsertValues, InsertCopy} {
+		b.Run(string(mode), func(b *testing.B) {
+			scratch, err := store.Scratch(ctx, "market_data_bench_test")
+			if err != nil {
+				b.Fatalf("create scratch table: %v", err)
+			}
+			defer scratch.DropScratch(ctx)
+			scratch.SetInsertMode(mode)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := scratch.InsertBatch(ctx, batch); err != nil {
+					b.Fatalf("insert: %v", err)
+				}
+			}
+			b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
+
+func benchTicks(n int) []models.MarketData {
+	base := time.Now().UnixMilli()
+	ticks := make([]models.MarketData, n)
+	for i := range ticks {
+		ltp := 1000 + float64(i%5000)/20
+		ticks[i] = models.MarketData{
+			Name:      "BANKNIFTY25DECFUT",
+			Timestamp: base + int64(i),
+			Exchange:  "nse",
+			Feed:      "bench",
+			Data: map[string]interface{}{
+				"ltp": ltp,
+				"bid": ltp - 0.05,
+				"ask": ltp + 0.05,
+				"qty": float64(1 + i%50),
+			},
+		}
+		ticks[i].Classify()
+	}
+	return ticks
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 	common "ws_ingestor/internal/app/common/exception_handler"
 	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/metrics"
 	"ws_ingestor/internal/app/models"
 
 	"github.com/redis/go-redis/v9"
@@ -34,8 +36,35 @@ func NewRedis(addr, password string, db int) (*CacheService, error) {
 	return &CacheService{Client: rdb, logger: logger.GetLogger()}, nil
 }
 
+// latestScript writes a tick unless the cached one for the symbol is newer,
+// so an out-of-order or backfilled tick never moves the latest value back in
+// time. KEYS[2], when present, is the option chain hash to update alongside.
+var latestScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur then
+	local ok, prev = pcall(cjson.decode, cur)
+	if ok and type(prev) == 'table' and tonumber(prev.timestamp) and tonumber(prev.timestamp) > tonumber(ARGV[2]) then
+		return 0
+	end
+end
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+if KEYS[2] then
+	redis.call('HSET', KEYS[2], KEYS[1], ARGV[1])
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+end
+return 1
+`)
+
 func (c *CacheService) InsertBatch(ctx context.Context, batch []models.MarketData, ttl time.Duration) error {
 	pipe := c.Client.Pipeline()
+	cmds := make([]*redis.Cmd, 0, len(batch))
 
 	for _, data := range batch {
 		if data.Timestamp == 0 {
@@ -49,7 +78,11 @@ func (c *CacheService) InsertBatch(ctx context.Context, batch []models.MarketDat
 			continue
 		}
 
-		pipe.Set(ctx, key, value, ttl)
+		keys := []string{key}
+		if data.Option != nil {
+			keys = append(keys, ChainKey(data.Option.Underlying, data.Option.ExpiryDate()))
+		}
+		cmds = append(cmds, latestScript.Eval(ctx, pipe, keys, value, data.Timestamp, ttl.Milliseconds()))
 	}
 
 	_, err := pipe.Exec(ctx)
@@ -57,6 +90,11 @@ func (c *CacheService) InsertBatch(ctx context.Context, batch []models.MarketDat
 		c.logger.Error(fmt.Sprintf("Failed to execute Redis pipeline: %v", err))
 		return err
 	}
+	for _, cmd := range cmds {
+		if n, _ := cmd.Int(); n == 0 {
+			metrics.StaleCacheWrites.Inc()
+		}
+	}
 	return nil
 }
 
@@ -67,7 +105,8 @@ func (c *CacheService) Close() {
 func (c *CacheService) GetAllData(ctx context.Context) ([]models.MarketData, error) {
 	var allData []models.MarketData
 
-	iter := c.Client.Scan(ctx, 0, "*", 0).Iterator()
+	// Only string keys hold ticks; chain snapshots are hashes
+	iter := c.Client.ScanType(ctx, 0, "*", 0, "string").Iterator()
 	for iter.Next(ctx) {
 		key := iter.Val()
 		value, err := c.Client.Get(ctx, key).Result()
@@ -76,8 +115,8 @@ func (c *CacheService) GetAllData(ctx context.Context) ([]models.MarketData, err
 			continue
 		}
 
-		var data models.MarketData
-		if err := json.Unmarshal([]byte(value), &data); err != nil {
+		data, err := decodeTick(value)
+		if err != nil {
 			c.logger.Error(fmt.Sprintf("Failed to unmarshal data for key %s: %v", key, err))
 			continue
 		}
@@ -91,3 +130,50 @@ func (c *CacheService) GetAllData(ctx context.Context) ([]models.MarketData, err
 
 	return allData, nil
 }
+
+// ChainKey is the Redis hash holding the latest tick of every option of one
+// underlying and expiry (YYYY-MM-DD), keyed by symbol.
+func ChainKey(underlying, expiry string) string {
+	return "chain:" + underlying + ":" + expiry
+}
+
+// Get returns the latest tick of symbol; ok is false when none is cached.
+func (c *CacheService) Get(ctx context.Context, symbol string) (models.MarketData, bool, error) {
+	value, err := c.Client.Get(ctx, symbol).Result()
+	if err == redis.Nil {
+		return models.MarketData{}, false, nil
+	}
+	if err != nil {
+		return models.MarketData{}, false, err
+	}
+	data, err := decodeTick(value)
+	return data, err == nil, err
+}
+
+// GetChain returns the cached chain snapshot of underlying and expiry,
+// keyed by option symbol.
+func (c *CacheService) GetChain(ctx context.Context, underlying, expiry string) (map[string]models.MarketData, error) {
+	values, err := c.Client.HGetAll(ctx, ChainKey(underlying, expiry)).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]models.MarketData, len(values))
+	for symbol, value := range values {
+		data, err := decodeTick(value)
+		if err != nil {
+			c.logger.Error(fmt.Sprintf("Failed to unmarshal chain entry %s: %v", symbol, err))
+			continue
+		}
+		out[symbol] = data
+	}
+	return out, nil
+}
+
+// decodeTick keeps numbers as json.Number so prices stay exact.
+func decodeTick(value string) (models.MarketData, error) {
+	var data models.MarketData
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	err := dec.Decode(&data)
+	return data, err
+}
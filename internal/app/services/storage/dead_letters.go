@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+func (s *Store) PutDeadLetter(ctx context.Context, d models.DeadLetter) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO `+constants.DEAD_LETTERS_TABLE_NAME+` (feed, symbol, stage, reason, raw, received_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+	`, d.Feed, d.Symbol, d.Stage, d.Reason, d.Raw, d.ReceivedAt)
+	return err
+}
+
+// ListDeadLetters returns matching dead letters, oldest first.
+func (s *Store) ListDeadLetters(ctx context.Context, f models.DeadLetterFilter) ([]models.DeadLetter, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if len(f.IDs) > 0 {
+		add("id = ANY($%d)", pq.Array(f.IDs))
+	}
+	if f.Feed != "" {
+		add("feed = $%d", f.Feed)
+	}
+	if f.Stage != "" {
+		add("stage = $%d", f.Stage)
+	}
+	if f.Symbol != "" {
+		add("symbol = $%d", f.Symbol)
+	}
+	if !f.Since.IsZero() {
+		add("received_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("received_at < $%d", f.Until)
+	}
+	if !f.IncludeRedriven {
+		where = append(where, "redriven_at IS NULL")
+	}
+
+	query := `SELECT id, feed, COALESCE(symbol, ''), stage, reason, raw, received_at, redriven_at FROM ` + constants.DEAD_LETTERS_TABLE_NAME
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id`
+	if f.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.DeadLetter
+	for rows.Next() {
+		var (
+			d        models.DeadLetter
+			redriven sql.NullTime
+		)
+		if err := rows.Scan(&d.ID, &d.Feed, &d.Symbol, &d.Stage, &d.Reason, &d.Raw, &d.ReceivedAt, &redriven); err != nil {
+			return nil, err
+		}
+		if redriven.Valid {
+			t := redriven.Time
+			d.RedrivenAt = &t
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) MarkRedriven(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE `+constants.DEAD_LETTERS_TABLE_NAME+` SET redriven_at = now() WHERE id = ANY($1)
+	`, pq.Array(ids))
+	return err
+}
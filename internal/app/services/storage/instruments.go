@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+)
+
+// LoadInstruments returns the whole instrument master.
+func (s *Store) LoadInstruments(ctx context.Context) ([]models.Instrument, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT symbol, exchange, COALESCE(asset_class, ''), COALESCE(tick_size, 0), COALESCE(lot_size, 0),
+		       COALESCE(precision, 0), expiry, COALESCE(currency, ''), active, updated_at,
+		       COALESCE(underlying, ''), strike, COALESCE(option_right, '')
+		FROM `+constants.INSTRUMENTS_TABLE_NAME)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Instrument
+	for rows.Next() {
+		var (
+			in     models.Instrument
+			expiry sql.NullTime
+			strike sql.NullString
+		)
+		if err := rows.Scan(&in.Symbol, &in.Exchange, &in.AssetClass, &in.TickSize, &in.LotSize,
+			&in.Precision, &expiry, &in.Currency, &in.Active, &in.UpdatedAt,
+			&in.Underlying, &strike, &in.Right); err != nil {
+			return nil, err
+		}
+		if strike.Valid {
+			d, err := models.ParseDecimal(strike.String)
+			if err != nil {
+				return nil, fmt.Errorf("instrument %s: %w", in.Symbol, err)
+			}
+			in.Strike = &d
+		}
+		if expiry.Valid {
+			t := expiry.Time
+			in.Expiry = &t
+		}
+		out = append(out, in)
+	}
+	return out, rows.Err()
+}
+
+// UpsertInstruments inserts or replaces instruments by symbol.
+func (s *Store) UpsertInstruments(ctx context.Context, list []models.Instrument) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO `+constants.INSTRUMENTS_TABLE_NAME+`
+			(symbol, exchange, asset_class, tick_size, lot_size, precision, expiry, currency, active,
+			 underlying, strike, option_right, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), now())
+		ON CONFLICT (symbol) DO UPDATE SET
+			exchange = EXCLUDED.exchange,
+			asset_class = EXCLUDED.asset_class,
+			tick_size = EXCLUDED.tick_size,
+			lot_size = EXCLUDED.lot_size,
+			precision = EXCLUDED.precision,
+			expiry = EXCLUDED.expiry,
+			currency = EXCLUDED.currency,
+			active = EXCLUDED.active,
+			underlying = EXCLUDED.underlying,
+			strike = EXCLUDED.strike,
+			option_right = EXCLUDED.option_right,
+			updated_at = now()`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, in := range list {
+		var strike any
+		if in.Strike != nil {
+			strike = *in.Strike
+		}
+		if _, err := stmt.ExecContext(ctx, in.Symbol, in.Exchange, in.AssetClass, in.TickSize, in.LotSize,
+			in.Precision, in.Expiry, in.Currency, in.Active, in.Underlying, strike, in.Right); err != nil {
+			return fmt.Errorf("upsert %s: %w", in.Symbol, err)
+		}
+	}
+	return tx.Commit()
+}
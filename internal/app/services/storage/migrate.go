@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock ("ws_mig") is the advisory lock key held while migrating, so
+// replicas starting together apply each migration once.
+const migrationLock int64 = 0x77735f6d6967
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change compiled into the binary.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus is a known migration and when it was applied; AppliedAt is
+// nil for pending ones.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := migrationFiles.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up script", mig)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// LatestMigration is the highest embedded migration version.
+func LatestMigration() int64 {
+	list, err := Migrations()
+	if err != nil || len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1].Version
+}
+
+// Migrate applies every pending migration and returns the ones applied.
+func (s *Store) Migrate(ctx context.Context) ([]Migration, error) {
+	return s.MigrateTo(ctx, LatestMigration())
+}
+
+// MigrateTo moves the schema to version: pending migrations up to it are
+// applied in order, applied ones above it are reverted newest first. Each
+// migration runs in its own transaction under an advisory lock.
+func (s *Store) MigrateTo(ctx context.Context, version int64) ([]Migration, error) {
+	var done []Migration
+	err := s.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		done, err = s.migrateTo(ctx, conn, version)
+		return err
+	})
+	return done, err
+}
+
+// withMigrationLock runs fn on a connection holding the migration advisory
+// lock. The lock belongs to that session, so anything fn does through the
+// pool is still serialized against other replicas.
+func (s *Store) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+	return fn(conn)
+}
+
+// migrateTo is MigrateTo on a connection that already holds the lock.
+func (s *Store) migrateTo(ctx context.Context, conn *sql.Conn, version int64) ([]Migration, error) {
+	list, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		)`); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range list {
+		if _, ok := applied[m.Version]; ok || m.Version > version {
+			continue
+		}
+		if err := s.runMigration(ctx, conn, m, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return done, err
+		}
+		s.logger.Info(fmt.Sprintf("Applied migration %s", m))
+		done = append(done, m)
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		m := list[i]
+		if _, ok := applied[m.Version]; !ok || m.Version <= version {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %s cannot be reverted: no down script", m)
+		}
+		if err := s.runMigration(ctx, conn, m, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+			return done, err
+		}
+		s.logger.Info(fmt.Sprintf("Reverted migration %s", m))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+func (s *Store) runMigration(ctx context.Context, conn *sql.Conn, m Migration, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %s: %w", m, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("migration %s: %w", m, err)
+	}
+	return tx.Commit()
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func appliedMigrations(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version int64
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		out[version] = at
+	}
+	return out, rows.Err()
+}
+
+// MigrationStatus lists the embedded migrations and when each was applied.
+func (s *Store) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	list, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]time.Time{}
+	if kind, err := s.relkind(ctx, "schema_migrations"); err != nil {
+		return nil, err
+	} else if kind != "" {
+		if applied, err = appliedMigrations(ctx, s.db); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		st := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
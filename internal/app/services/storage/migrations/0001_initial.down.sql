@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS clients_configs;
+DROP TABLE IF EXISTS market_data;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS market_data (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    timestamp BIGINT NOT NULL,
+    exchange VARCHAR(100),
+    data JSONB
+);
+
+CREATE TABLE IF NOT EXISTS clients_configs (
+    id VARCHAR(255) PRIMARY KEY,
+    config JSONB
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    key_hash VARCHAR(255) UNIQUE NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    last_used_at TIMESTAMP
+);
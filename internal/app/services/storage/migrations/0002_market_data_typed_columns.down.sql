@@ -0,0 +1,7 @@
+ALTER TABLE market_data
+    DROP COLUMN IF EXISTS tick,
+    DROP COLUMN IF EXISTS ask,
+    DROP COLUMN IF EXISTS bid,
+    DROP COLUMN IF EXISTS price,
+    DROP COLUMN IF EXISTS kind,
+    DROP COLUMN IF EXISTS feed;
@@ -0,0 +1,7 @@
+ALTER TABLE market_data
+    ADD COLUMN IF NOT EXISTS feed VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS kind VARCHAR(16),
+    ADD COLUMN IF NOT EXISTS price NUMERIC,
+    ADD COLUMN IF NOT EXISTS bid NUMERIC,
+    ADD COLUMN IF NOT EXISTS ask NUMERIC,
+    ADD COLUMN IF NOT EXISTS tick JSONB;
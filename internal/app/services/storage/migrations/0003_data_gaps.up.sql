@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS data_gaps (
+    id SERIAL PRIMARY KEY,
+    feed VARCHAR(100) NOT NULL,
+    symbol VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    from_ts BIGINT NOT NULL,
+    to_ts BIGINT NOT NULL,
+    from_seq BIGINT,
+    to_seq BIGINT,
+    detected_at TIMESTAMP NOT NULL DEFAULT now(),
+    filled_at TIMESTAMP,
+    filled_count INT
+);
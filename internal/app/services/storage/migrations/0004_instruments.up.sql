@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS instruments (
+    symbol VARCHAR(255) PRIMARY KEY,
+    exchange VARCHAR(100) NOT NULL,
+    asset_class VARCHAR(32),
+    tick_size NUMERIC,
+    lot_size NUMERIC,
+    precision INT,
+    expiry DATE,
+    currency VARCHAR(16),
+    active BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
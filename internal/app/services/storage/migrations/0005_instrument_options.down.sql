@@ -0,0 +1,4 @@
+ALTER TABLE instruments
+    DROP COLUMN IF EXISTS option_right,
+    DROP COLUMN IF EXISTS strike,
+    DROP COLUMN IF EXISTS underlying;
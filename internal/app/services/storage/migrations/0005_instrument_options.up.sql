@@ -0,0 +1,4 @@
+ALTER TABLE instruments
+    ADD COLUMN IF NOT EXISTS underlying VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS strike NUMERIC,
+    ADD COLUMN IF NOT EXISTS option_right VARCHAR(2);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS roll_events;
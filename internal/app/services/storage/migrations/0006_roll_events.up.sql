@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS roll_events (
+    id SERIAL PRIMARY KEY,
+    root VARCHAR(255) NOT NULL,
+    alias VARCHAR(255) NOT NULL,
+    from_symbol VARCHAR(255) NOT NULL,
+    to_symbol VARCHAR(255) NOT NULL,
+    rule VARCHAR(16) NOT NULL,
+    reason TEXT,
+    rolled_at TIMESTAMP NOT NULL DEFAULT now()
+);
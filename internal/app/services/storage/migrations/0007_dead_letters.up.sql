@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    feed VARCHAR(100) NOT NULL,
+    symbol VARCHAR(255),
+    stage VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL,
+    raw BYTEA,
+    received_at TIMESTAMP NOT NULL DEFAULT now(),
+    redriven_at TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS market_data_name_ts_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS market_data_name_ts_idx ON market_data (name, timestamp);
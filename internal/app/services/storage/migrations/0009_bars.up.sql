@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS bars (
+    symbol VARCHAR(255) NOT NULL,
+    exchange VARCHAR(100),
+    interval VARCHAR(8) NOT NULL,
+    start_ts BIGINT NOT NULL,
+    end_ts BIGINT NOT NULL,
+    open NUMERIC NOT NULL,
+    high NUMERIC NOT NULL,
+    low NUMERIC NOT NULL,
+    close NUMERIC NOT NULL,
+    volume NUMERIC,
+    ticks INT NOT NULL,
+    PRIMARY KEY (symbol, interval, start_ts)
+);
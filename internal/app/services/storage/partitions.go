@@ -0,0 +1,388 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	common "ws_ingestor/internal/app/common/exception_handler"
+	"ws_ingestor/internal/app/metrics"
+)
+
+// PartitionPeriod is the range covered by one market data partition.
+type PartitionPeriod string
+
+const (
+	PartitionNone PartitionPeriod = "none"
+	PartitionDay  PartitionPeriod = "day"
+	PartitionWeek PartitionPeriod = "week"
+)
+
+// PartitionOptions configures range partitioning of the market data table on
+// its millisecond timestamp. Periods start at UTC midnight; weeks on Monday.
+type PartitionOptions struct {
+	Period PartitionPeriod
+	// Premake is the number of partitions created ahead of the current one.
+	Premake int
+	// Retention drops partitions whose range ended longer ago; zero keeps
+	// everything.
+	Retention time.Duration
+	// DetachOnly detaches expired partitions without dropping them.
+	DetachOnly bool
+}
+
+func (o PartitionOptions) enabled() bool {
+	return o.Period == PartitionDay || o.Period == PartitionWeek
+}
+
+func (o PartitionOptions) start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if o.Period == PartitionWeek {
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+func (o PartitionOptions) next(t time.Time) time.Time {
+	if o.Period == PartitionWeek {
+		return t.AddDate(0, 0, 7)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+type partition struct {
+	name     string
+	from, to int64
+}
+
+var boundPattern = regexp.MustCompile(`FROM \(([^)]*)\) TO \(([^)]*)\)`)
+
+func parseBound(s string) (int64, error) {
+	switch s = strings.Trim(s, "'"); s {
+	case "MINVALUE":
+		return math.MinInt64, nil
+	case "MAXVALUE":
+		return math.MaxInt64, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// relkind reports the kind of table: "p" when partitioned, "r" for a plain
+// table and "" when it does not exist.
+func (s *Store) relkind(ctx context.Context, table string) (string, error) {
+	var kind string
+	err := s.db.QueryRowContext(ctx, `SELECT relkind FROM pg_class WHERE oid = to_regclass($1)`, table).Scan(&kind)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return kind, err
+}
+
+// ensurePartitioned creates the market data table partitioned by range on
+// timestamp. An existing plain table is converted in place: it is renamed to
+// <table>_legacy and attached as the partition holding everything up to the
+// end of the current period, so no rows are copied.
+func (s *Store) ensurePartitioned(ctx context.Context, table string) error {
+	kind, err := s.relkind(ctx, table)
+	if err != nil {
+		return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to inspect table %s", table), err)
+	}
+	switch kind {
+	case "p":
+		return nil
+	case "":
+		query := `CREATE TABLE IF NOT EXISTS ` + table + ` (
+				id BIGSERIAL,
+				name VARCHAR(255) NOT NULL,
+				timestamp BIGINT NOT NULL,
+				exchange VARCHAR(100),
+				feed VARCHAR(100),
+				kind VARCHAR(16),
+				price NUMERIC,
+				bid NUMERIC,
+				ask NUMERIC,
+				tick JSONB,
+				data JSONB,
+				PRIMARY KEY (id, timestamp)
+			) PARTITION BY RANGE (timestamp)`
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to create table %s", table), err)
+		}
+		s.logger.Info(fmt.Sprintf("Ensured partitioned table %s exists", table))
+		return nil
+	}
+
+	if _, err := s.db.ExecContext(ctx, marketDataUpgrade(table)); err != nil {
+		return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to add columns to %s", table), err)
+	}
+	if err := s.convertToPartitioned(ctx, table); err != nil {
+		return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to partition table %s", table), err)
+	}
+	return nil
+}
+
+func (s *Store) convertToPartitioned(ctx context.Context, table string) error {
+	legacy := table + "_legacy"
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		seq    sql.NullString
+		idType string
+		maxTs  sql.NullInt64
+	)
+	if err := tx.QueryRowContext(ctx, `SELECT pg_get_serial_sequence($1, 'id')`, table).Scan(&seq); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = to_regclass($1) AND attname = 'id'`, table).Scan(&idType); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT max(timestamp) FROM `+table).Scan(&maxTs); err != nil {
+		return err
+	}
+	if !seq.Valid {
+		seq = sql.NullString{String: table + "_id_seq", Valid: true}
+		if _, err := tx.ExecContext(ctx, `CREATE SEQUENCE IF NOT EXISTS `+seq.String); err != nil {
+			return err
+		}
+	}
+
+	// The legacy partition ends where the first regular one starts
+	last := time.Now()
+	if maxTs.Valid && maxTs.Int64 > last.UnixMilli() {
+		last = time.UnixMilli(maxTs.Int64)
+	}
+	bound := s.partitions.next(s.partitions.start(last)).UnixMilli()
+
+	// A partition can't keep a primary key that omits the partition key, and
+	// dropping the legacy table later must not take the id sequence with it
+	for _, query := range []string{
+		`ALTER TABLE ` + table + ` RENAME TO ` + legacy,
+		`ALTER TABLE ` + legacy + ` DROP CONSTRAINT IF EXISTS ` + table + `_pkey`,
+		`ALTER INDEX IF EXISTS ` + table + `_dedup_idx RENAME TO ` + legacy + `_dedup_idx`,
+		`ALTER INDEX IF EXISTS ` + table + `_name_ts_idx RENAME TO ` + legacy + `_name_ts_idx`,
+		`ALTER SEQUENCE ` + seq.String + ` OWNED BY NONE`,
+		`CREATE TABLE ` + table + ` (
+				id ` + idType + ` NOT NULL DEFAULT nextval('` + seq.String + `'),
+				name VARCHAR(255) NOT NULL,
+				timestamp BIGINT NOT NULL,
+				exchange VARCHAR(100),
+				feed VARCHAR(100),
+				kind VARCHAR(16),
+				price NUMERIC,
+				bid NUMERIC,
+				ask NUMERIC,
+				tick JSONB,
+				data JSONB,
+				PRIMARY KEY (id, timestamp)
+			) PARTITION BY RANGE (timestamp)`,
+		`ALTER SEQUENCE ` + seq.String + ` OWNED BY ` + table + `.id`,
+		`ALTER TABLE ` + table + ` ATTACH PARTITION ` + legacy + ` FOR VALUES FROM (MINVALUE) TO (` + strconv.FormatInt(bound, 10) + `)`,
+		// Migration 0008 may already be recorded, so it won't index the new
+		// parent; an existing legacy index is attached rather than rebuilt
+		`CREATE INDEX IF NOT EXISTS ` + table + `_name_ts_idx ON ` + table + ` (name, timestamp)`,
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Info(fmt.Sprintf("Converted %s to a partitioned table; existing rows are in %s until %s", table, legacy, time.UnixMilli(bound).UTC().Format(time.RFC3339)))
+	return nil
+}
+
+func (s *Store) listPartitions(ctx context.Context) ([]partition, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.relname, pg_get_expr(c.relpartbound, c.oid)
+		FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass($1)
+	`, s.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []partition
+	for rows.Next() {
+		var name, bound string
+		if err := rows.Scan(&name, &bound); err != nil {
+			return nil, err
+		}
+		m := boundPattern.FindStringSubmatch(bound)
+		if m == nil {
+			continue // DEFAULT partition
+		}
+		p := partition{name: name}
+		if p.from, err = parseBound(m[1]); err != nil {
+			return nil, fmt.Errorf("partition %s: %w", name, err)
+		}
+		if p.to, err = parseBound(m[2]); err != nil {
+			return nil, fmt.Errorf("partition %s: %w", name, err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// EnsurePartitions creates the <table>_default partition, which takes ticks
+// outside every range, and the partition for the period containing now and
+// the next Premake ones, skipping ranges an existing partition already
+// covers. With unique ticks each partition gets its own dedup index.
+func (s *Store) EnsurePartitions(ctx context.Context, now time.Time) error {
+	def := s.table + "_default"
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+def+` PARTITION OF `+s.table+` DEFAULT`); err != nil {
+		return fmt.Errorf("create partition %s: %w", def, err)
+	}
+
+	parts, err := s.listPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	start := s.partitions.start(now)
+	for i := 0; i <= s.partitions.Premake; i++ {
+		end := s.partitions.next(start)
+		from, to := uncovered(parts, start.UnixMilli(), end.UnixMilli())
+		if from < to {
+			name := s.table + "_p" + time.UnixMilli(from).UTC().Format("20060102")
+			if err := s.createPartition(ctx, name, def, from, to); err != nil {
+				return fmt.Errorf("create partition %s: %w", name, err)
+			}
+			s.logger.Info(fmt.Sprintf("Created partition %s", name))
+			parts = append(parts, partition{name: name, from: from, to: to})
+		}
+		start = end
+	}
+
+	if s.uniqueTicks {
+		for _, p := range append(parts, partition{name: def}) {
+			query := `CREATE UNIQUE INDEX IF NOT EXISTS ` + p.name + `_dedup_idx ON ` + p.name + ` (name, timestamp, md5(data::text))`
+			if _, err := s.db.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("create unique index on %s (remove existing duplicates first): %w", p.name, err)
+			}
+		}
+	}
+	metrics.Partitions.Set(float64(len(parts)))
+	return nil
+}
+
+// createPartition adds the range partition [from, to). Postgres refuses it
+// while the default partition holds rows in that range, so those rows are
+// moved into the new partition with the default detached.
+func (s *Store) createPartition(ctx context.Context, name, def string, from, to int64) error {
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%d) TO (%d)`, name, s.table, from, to)
+	var strays bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+def+` WHERE timestamp >= $1 AND timestamp < $2)`, from, to).Scan(&strays); err != nil {
+		return err
+	}
+	if !strays {
+		_, err := s.db.ExecContext(ctx, create)
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rng := fmt.Sprintf(`timestamp >= %d AND timestamp < %d`, from, to)
+	for _, query := range []string{
+		`ALTER TABLE ` + s.table + ` DETACH PARTITION ` + def,
+		create,
+		`INSERT INTO ` + name + ` SELECT * FROM ` + def + ` WHERE ` + rng,
+		`DELETE FROM ` + def + ` WHERE ` + rng,
+		`ALTER TABLE ` + s.table + ` ATTACH PARTITION ` + def + ` DEFAULT`,
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Info(fmt.Sprintf("Moved rows from %s into %s", def, name))
+	return nil
+}
+
+// uncovered trims [from, to) to the part no existing partition covers. When
+// the period changes, ranges only partially overlap older partitions.
+func uncovered(parts []partition, from, to int64) (int64, int64) {
+	for moved := true; moved; {
+		moved = false
+		for _, p := range parts {
+			if p.from <= from && from < p.to {
+				from, moved = p.to, true
+			}
+		}
+	}
+	for _, p := range parts {
+		if from < p.from && p.from < to {
+			to = p.from
+		}
+	}
+	return from, to
+}
+
+// ExpirePartitions detaches, and unless DetachOnly drops, partitions whose
+// range ended more than Retention before now.
+func (s *Store) ExpirePartitions(ctx context.Context, now time.Time) error {
+	if s.partitions.Retention <= 0 {
+		return nil
+	}
+	parts, err := s.listPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-s.partitions.Retention).UnixMilli()
+	for _, p := range parts {
+		if p.to > cutoff {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, `ALTER TABLE `+s.table+` DETACH PARTITION `+p.name); err != nil {
+			return fmt.Errorf("detach partition %s: %w", p.name, err)
+		}
+		if s.partitions.DetachOnly {
+			s.logger.Info(fmt.Sprintf("Detached expired partition %s", p.name))
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, `DROP TABLE `+p.name); err != nil {
+			return fmt.Errorf("drop partition %s: %w", p.name, err)
+		}
+		s.logger.Info(fmt.Sprintf("Dropped expired partition %s", p.name))
+	}
+	return nil
+}
+
+// RunPartitionMaintenance creates upcoming partitions and expires old ones
+// every interval until ctx is done. It is a no-op without partitioning.
+func (s *Store) RunPartitionMaintenance(ctx context.Context, interval time.Duration) {
+	if !s.partitions.enabled() || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if err := s.ExpirePartitions(ctx, now); err != nil {
+				s.logger.Error(fmt.Sprintf("Partition retention failed: %v", err))
+			}
+			if err := s.EnsurePartitions(ctx, now); err != nil {
+				s.logger.Error(fmt.Sprintf("Partition maintenance failed: %v", err))
+			}
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"ws_ingestor/internal/app/common/logger"
 	"ws_ingestor/internal/app/constants"
 	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/metrics"
 	"ws_ingestor/internal/app/models"
 	"ws_ingestor/internal/utils"
 
@@ -19,11 +20,43 @@ import (
 )
 
 type Store struct {
-	db     *sql.DB
-	logger *logrus.Logger
+	db          *sql.DB
+	logger      *logrus.Logger
+	uniqueTicks bool
+	insertMode  InsertMode
+	table       string
+	partitions  PartitionOptions
 }
 
-func NewPostgres(dbURL string) (*Store, error) {
+// marketDataUpgrade adds the columns tables created by older versions lack.
+func marketDataUpgrade(table string) string {
+	return `ALTER TABLE ` + table + `
+			ADD COLUMN IF NOT EXISTS feed VARCHAR(100),
+			ADD COLUMN IF NOT EXISTS kind VARCHAR(16),
+			ADD COLUMN IF NOT EXISTS price NUMERIC,
+			ADD COLUMN IF NOT EXISTS bid NUMERIC,
+			ADD COLUMN IF NOT EXISTS ask NUMERIC,
+			ADD COLUMN IF NOT EXISTS tick JSONB`
+}
+
+// NewPostgres connects and migrates the schema to the latest version. With
+// uniqueTicks a unique index on (name, timestamp, payload hash) is created and
+// duplicate inserts are silently skipped. partitions selects range
+// partitioning of the market data table; its zero value keeps a single table.
+func NewPostgres(dbURL string, uniqueTicks bool, partitions PartitionOptions) (*Store, error) {
+	store, err := Connect(dbURL, uniqueTicks, partitions)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.EnsureSchema(context.Background()); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Connect opens the connection pool without touching the schema.
+func Connect(dbURL string, uniqueTicks bool, partitions PartitionOptions) (*Store, error) {
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		return nil, common.NewCustomError(common.ErrDBConnect, "Failed to connect to Postgres", err)
@@ -33,66 +66,65 @@ func NewPostgres(dbURL string) (*Store, error) {
 	db.SetMaxIdleConns(25)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
-	store := &Store{
-		db:     db,
-		logger: logger.GetLogger(),
+	return &Store{
+		db:          db,
+		logger:      logger.GetLogger(),
+		uniqueTicks: uniqueTicks,
+		insertMode:  InsertRow,
+		table:       constants.MARKET_DATA_TABLE_NAME,
+		partitions:  partitions,
+	}, nil
+}
+
+// EnsureSchema applies pending migrations. Partitioning and the unique-ticks
+// index depend on configuration rather than on the schema version, so they
+// are applied around the migrations. All of it runs under the migration
+// lock, so a replica starting alongside waits and then finds the table
+// already converted.
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	return s.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		// A partitioned market data table must exist before migration 0001
+		// would create a plain one
+		if s.partitions.enabled() {
+			if err := s.ensurePartitioned(ctx, s.table); err != nil {
+				return err
+			}
+		}
+		if _, err := s.migrateTo(ctx, conn, LatestMigration()); err != nil {
+			return common.NewCustomError(common.ErrDBConnect, "Failed to migrate schema", err)
+		}
+		return s.ensureLayout(ctx)
+	})
+}
+
+// CheckSchema fails when migrations are pending, for deployments that run
+// `migrate up` before rolling out; otherwise it behaves like EnsureSchema.
+func (s *Store) CheckSchema(ctx context.Context) error {
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		return common.NewCustomError(common.ErrDBConnect, "Failed to read schema version", err)
 	}
-	if err := store.createTables(); err != nil {
-		return nil, err
+	for _, st := range status {
+		if st.AppliedAt == nil {
+			return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Migration %s is pending; run `ws_ingestor migrate up`", st.Migration), nil)
+		}
 	}
-	return store, nil
+	return s.ensureLayout(ctx)
 }
 
-func (s *Store) createTables() error {
-
-	tableName := constants.MARKET_DATA_TABLE_NAME
-	if tableName == "" {
-		tableName = "market_data"
-	}
-	query := `CREATE TABLE IF NOT EXISTS ` + tableName + ` (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			timestamp BIGINT NOT NULL,
-			exchange VARCHAR(100),
-			data JSONB
-		)`
-	if _, err := s.db.Exec(query); err != nil {
-		return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to create table %s", tableName), err)
-	} else {
-		s.logger.Info(fmt.Sprintf("Ensured table %s exists", tableName))
-	}
-
-	clientsTable := constants.CLIENTS_CONFIGS_TABLE_NAME
-	if clientsTable == "" {
-		clientsTable = "clients_configs"
-	}
-	query = `CREATE TABLE IF NOT EXISTS ` + clientsTable + ` (
-			id VARCHAR(255) PRIMARY KEY,
-			config JSONB
-		)`
-	if _, err := s.db.Exec(query); err != nil {
-		return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to create table %s", clientsTable), err)
-	} else {
-		s.logger.Info(fmt.Sprintf("Ensured table %s exists", clientsTable))
-	}
-
-	apiKeysTable := constants.API_KEYS_TABLE_NAME
-	if apiKeysTable == "" {
-		apiKeysTable = "api_keys"
-	}
-	query = `CREATE TABLE IF NOT EXISTS ` + apiKeysTable + ` (
-			id SERIAL PRIMARY KEY,
-			client_id VARCHAR(255) NOT NULL,
-			key_hash VARCHAR(255) UNIQUE NOT NULL,
-			is_active BOOLEAN DEFAULT true,
-			last_used_at TIMESTAMP
-		)`
-	if _, err := s.db.Exec(query); err != nil {
-		return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to create table %s", apiKeysTable), err)
-	} else {
-		s.logger.Info(fmt.Sprintf("Ensured table %s exists", apiKeysTable))
+func (s *Store) ensureLayout(ctx context.Context) error {
+	// Unique indexes on a partitioned table can't hold the payload hash, so
+	// EnsurePartitions puts one on every partition instead
+	if s.partitions.enabled() {
+		if err := s.EnsurePartitions(ctx, time.Now()); err != nil {
+			return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to create partitions of %s", s.table), err)
+		}
+	} else if s.uniqueTicks {
+		query := `CREATE UNIQUE INDEX IF NOT EXISTS ` + s.table + `_dedup_idx ON ` + s.table + ` (name, timestamp, md5(data::text))`
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return common.NewCustomError(common.ErrDBConnect, fmt.Sprintf("Failed to create unique index on %s (remove existing duplicates first)", s.table), err)
+		}
 	}
-
 	return nil
 }
 
@@ -100,39 +132,103 @@ func (s *Store) Close() {
 	s.db.Close()
 }
 
-func (s *Store) InsertBatch(ctx context.Context, batch []models.MarketData) error {
+// InsertBatch writes batch to the market data table in one transaction using
+// the configured InsertMode. Records without a timestamp and alias copies
+// are skipped.
+//
+// Rows the database refuses on their own merits (bad data, constraint
+// violations) are isolated by bisecting the batch under savepoints: the rest
+// is committed and the offenders are returned with their error. Any other
+// error rolls back the whole batch.
+func (s *Store) InsertBatch(ctx context.Context, batch []models.MarketData) ([]Rejected, error) {
+	rows := make([]models.MarketData, 0, len(batch))
+	index := make([]int, 0, len(batch))
+	for i, record := range batch {
+		if record.Timestamp != 0 && !record.Alias {
+			rows = append(rows, record)
+			index = append(index, i)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Failed to begin transaction: %v", err))
-		return err
+		return nil, err
 	}
 	defer tx.Rollback() // Ensure rollback on error
 
-	tableName := constants.MARKET_DATA_TABLE_NAME
-	stmt, err := tx.PrepareContext(ctx,
-		`INSERT INTO `+tableName+` (name, timestamp, exchange, data) VALUES ($1,$2,$3,$4)`)
+	inserted, rejected, err := s.writeIsolated(ctx, tx, rows, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to commit transaction: %v", err))
+		return nil, err
+	}
+	for i, r := range rejected {
+		rejected[i].Index = index[r.Index]
+		metrics.RejectedRows.WithLabelValues(ErrorClass(r.Err)).Inc()
+	}
+	if stored := int64(len(rows) - len(rejected)); s.uniqueTicks && inserted < stored {
+		metrics.DuplicatesDropped.WithLabelValues("store").Add(float64(stored - inserted))
+	}
+	return rejected, nil
+}
+
+// write inserts rows with the configured InsertMode.
+func (s *Store) write(ctx context.Context, tx *sql.Tx, rows []models.MarketData) (int64, error) {
+	switch s.insertMode {
+	case InsertCopy:
+		return s.copyRows(ctx, tx, rows)
+	case InsertValues:
+		return s.insertValues(ctx, tx, rows)
+	default:
+		return s.insertRows(ctx, tx, rows)
+	}
+}
+
+// insertRows executes a prepared single-row INSERT per record.
+func (s *Store) insertRows(ctx context.Context, tx *sql.Tx, rows []models.MarketData) (int64, error) {
+	query := `INSERT INTO ` + s.table + ` (` + marketDataColumns + `) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+	if s.uniqueTicks {
+		query += ` ON CONFLICT DO NOTHING`
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("Failed to prepare statement for table %s: %v", tableName, err))
-		return err
+		s.logger.Error(fmt.Sprintf("Failed to prepare statement for table %s: %v", s.table, err))
+		return 0, err
 	}
 	defer stmt.Close()
 
-	for _, record := range batch {
-		dataBytes, _ := json.Marshal(record.Data)
-		if record.Timestamp == 0 {
-			continue // Skip entries with zero timestamp
-		}
-		if _, err := stmt.ExecContext(ctx, record.Name, record.Timestamp, record.Exchange, dataBytes); err != nil {
+	var inserted int64
+	for _, record := range rows {
+		res, err := stmt.ExecContext(ctx, rowValues(record)...)
+		if err != nil {
 			s.logger.Error(fmt.Sprintf("Failed to insert %s: %v", record.Name, err))
-			return err
+			return 0, err
 		}
+		n, _ := res.RowsAffected()
+		inserted += n
 	}
+	return inserted, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		s.logger.Error(fmt.Sprintf("Failed to commit transaction: %v", err))
-		return err
+// headline extracts the NUMERIC price columns; nil values are stored as NULL.
+func headline(m models.MarketData) (price, bid, ask any) {
+	switch {
+	case m.Trade != nil:
+		price = m.Trade.Price
+	case m.Bar != nil:
+		price = m.Bar.Close
 	}
-	return nil
+	if m.Quote != nil {
+		bid, ask = m.Quote.Bid, m.Quote.Ask
+	}
+	return price, bid, ask
 }
 
 func (s *Store) ValidateApiKey(ctx context.Context, apiKey string) (ClientID string, err error) {
@@ -180,6 +276,54 @@ func (s *Store) GetClientConfig(ctx context.Context, clientID string) (*dto.Clie
 	if err := json.Unmarshal(configJSON, &config); err != nil {
 		return nil, err
 	}
-	fmt.Println("GetClientConfig =  =================================== ", config)
 	return &config, nil
 }
+
+func (s *Store) RecordGap(ctx context.Context, gap models.DataGap) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO `+constants.DATA_GAPS_TABLE_NAME+` (feed, symbol, kind, from_ts, to_ts, from_seq, to_seq, detected_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, 0), $8)
+		RETURNING id
+	`, gap.Feed, gap.Symbol, gap.Kind, gap.From, gap.To, gap.FromSeq, gap.ToSeq, gap.DetectedAt).Scan(&id)
+	return id, err
+}
+
+func (s *Store) MarkGapFilled(ctx context.Context, id int64, records int) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE `+constants.DATA_GAPS_TABLE_NAME+` SET filled_at = now(), filled_count = $2
+		WHERE id = $1
+	`, id, records)
+	return err
+}
+
+func (s *Store) RecordRoll(ctx context.Context, ev models.RollEvent) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO `+constants.ROLL_EVENTS_TABLE_NAME+` (root, alias, from_symbol, to_symbol, rule, reason, rolled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, ev.Root, ev.Alias, ev.From, ev.To, ev.Rule, ev.Reason, ev.RolledAt)
+	return err
+}
+
+// LatestRolls returns the most recent roll event of every root.
+func (s *Store) LatestRolls(ctx context.Context) ([]models.RollEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (root) root, alias, from_symbol, to_symbol, rule, COALESCE(reason, ''), rolled_at
+		FROM `+constants.ROLL_EVENTS_TABLE_NAME+`
+		ORDER BY root, rolled_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.RollEvent
+	for rows.Next() {
+		var ev models.RollEvent
+		if err := rows.Scan(&ev.Root, &ev.Alias, &ev.From, &ev.To, &ev.Rule, &ev.Reason, &ev.RolledAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
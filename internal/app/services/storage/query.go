@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// Range selects rows of Symbols (all symbols when empty) with From <= time
+// < To in Unix ms; zero leaves a bound open. Interval is used for bars only.
+type Range struct {
+	Symbols  []string
+	Interval string
+	From     int64
+	To       int64
+}
+
+func (r Range) where(symbolCol, timeCol string) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if len(r.Symbols) > 0 {
+		add(symbolCol+" = ANY($%d)", pq.Array(r.Symbols))
+	}
+	if r.From > 0 {
+		add(timeCol+" >= $%d", r.From)
+	}
+	if r.To > 0 {
+		add(timeCol+" < $%d", r.To)
+	}
+	if r.Interval != "" {
+		add("interval = $%d", r.Interval)
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(where, " AND "), args
+}
+
+// TickCursor is the position after the last tick of a page. Ticks are read
+// in (timestamp, id) order so pages stay stable while new rows arrive.
+type TickCursor struct {
+	Timestamp int64
+	ID        int64
+}
+
+// TickQuery selects the ticks in Range, resuming after After when set.
+type TickQuery struct {
+	Range
+	After *TickCursor
+	Limit int
+}
+
+const tickColumns = `id, name, timestamp, exchange, COALESCE(feed, ''), COALESCE(kind, ''), tick, data`
+
+// QueryTicks returns up to q.Limit ticks oldest first, and the cursor of the
+// next page or nil when there is none.
+func (s *Store) QueryTicks(ctx context.Context, q TickQuery) ([]models.MarketData, *TickCursor, error) {
+	where, args := q.where("name", "timestamp")
+	if q.After != nil {
+		cond := fmt.Sprintf("(timestamp, id) > ($%d, $%d)", len(args)+1, len(args)+2)
+		if where == "" {
+			where = ` WHERE ` + cond
+		} else {
+			where += ` AND ` + cond
+		}
+		args = append(args, q.After.Timestamp, q.After.ID)
+	}
+	query := `SELECT ` + tickColumns + ` FROM ` + s.table + where + fmt.Sprintf(` ORDER BY timestamp, id LIMIT %d`, q.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		out []models.MarketData
+		ids []int64
+	)
+	for rows.Next() {
+		id, m, err := scanTick(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, ids = append(out, m), append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(out) <= q.Limit {
+		return out, nil, nil
+	}
+	out = out[:q.Limit]
+	last := len(out) - 1
+	return out, &TickCursor{Timestamp: out[last].Timestamp, ID: ids[last]}, nil
+}
+
+// LatestTick returns the newest stored tick of symbol; ok is false when
+// there is none.
+func (s *Store) LatestTick(ctx context.Context, symbol string) (models.MarketData, bool, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+tickColumns+` FROM `+s.table+`
+		WHERE name = $1 ORDER BY timestamp DESC, id DESC LIMIT 1`, symbol)
+	_, m, err := scanTick(row)
+	if err == sql.ErrNoRows {
+		return models.MarketData{}, false, nil
+	}
+	return m, err == nil, err
+}
+
+func scanTick(row interface{ Scan(...any) error }) (int64, models.MarketData, error) {
+	var (
+		id         int64
+		m          models.MarketData
+		kind       string
+		tick, data []byte
+	)
+	if err := row.Scan(&id, &m.Name, &m.Timestamp, &m.Exchange, &m.Feed, &kind, &tick, &data); err != nil {
+		return 0, m, err
+	}
+	m.Kind = models.TickKind(kind)
+	// The typed column holds the quote/trade/bar/depth views under the same
+	// keys as MarketData
+	if len(tick) > 0 {
+		if err := decodeNumbers(tick, &m); err != nil {
+			return 0, m, err
+		}
+	}
+	if err := decodeNumbers(data, &m.Data); err != nil {
+		return 0, m, err
+	}
+	return id, m, nil
+}
+
+// decodeNumbers keeps numbers as json.Number so prices stay exact.
+func decodeNumbers(raw []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// CandleQuery selects the stored bars of Symbol and Interval starting in
+// [From, To) (Unix ms; zero leaves a bound open), after the bar starting at
+// After when it is non-zero.
+type CandleQuery struct {
+	Symbol   string
+	Interval string
+	From     int64
+	To       int64
+	After    int64
+	Limit    int
+}
+
+// where leaves out the bounds that are zero rather than comparing them in
+// SQL, where an untyped zero would make Postgres read the parameter as int4.
+func (q CandleQuery) where() (string, []any) {
+	from := q.From
+	if q.After > 0 && q.After >= from {
+		from = q.After + 1
+	}
+	r := Range{Interval: q.Interval, From: from, To: q.To}
+	if q.Symbol != "" {
+		r.Symbols = []string{q.Symbol}
+	}
+	return r.where("symbol", "start_ts")
+}
+
+// QueryCandles returns up to q.Limit closed bars oldest first, and the start
+// of the last one when another page follows (zero otherwise).
+func (s *Store) QueryCandles(ctx context.Context, q CandleQuery) ([]models.Candle, int64, error) {
+	where, args := q.where()
+	query := `SELECT ` + candleColumns + ` FROM ` + constants.BARS_TABLE_NAME + where + ` ORDER BY start_ts LIMIT ` + fmt.Sprint(q.Limit+1)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []models.Candle
+	for rows.Next() {
+		c, err := scanCandle(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(out) <= q.Limit {
+		return out, 0, nil
+	}
+	out = out[:q.Limit]
+	return out, out[len(out)-1].Start, nil
+}
+
+const candleColumns = `symbol, COALESCE(exchange, ''), interval, start_ts, end_ts, open, high, low, close, volume, ticks`
+
+func scanCandle(row interface{ Scan(...any) error }) (models.Candle, error) {
+	var (
+		c      models.Candle
+		volume *models.Decimal
+	)
+	err := row.Scan(&c.Symbol, &c.Exchange, &c.Interval, &c.Start, &c.End, &c.Open, &c.High, &c.Low, &c.Close, &volume, &c.Ticks)
+	c.Volume, c.Final = volume, true
+	return c, err
+}
+
+// StreamTicks calls fn with every tick in r in (timestamp, id) order as the
+// rows arrive, so memory use does not depend on the size of the range. An
+// error from fn stops the stream and is returned.
+func (s *Store) StreamTicks(ctx context.Context, r Range, fn func(models.MarketData) error) error {
+	where, args := r.where("name", "timestamp")
+	rows, err := s.db.QueryContext(ctx, `SELECT `+tickColumns+` FROM `+s.table+where+` ORDER BY timestamp, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		_, m, err := scanTick(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamCandles calls fn with every stored bar in r in (start, symbol)
+// order, like StreamTicks.
+func (s *Store) StreamCandles(ctx context.Context, r Range, fn func(models.Candle) error) error {
+	where, args := r.where("symbol", "start_ts")
+	rows, err := s.db.QueryContext(ctx, `SELECT `+candleColumns+` FROM `+constants.BARS_TABLE_NAME+where+` ORDER BY start_ts, symbol, interval`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c, err := scanCandle(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCandleQueryWhere(t *testing.T) {
+	tests := []struct {
+		name     string
+		q        CandleQuery
+		wantCond []string
+		wantArgs []any
+	}{
+		{
+			name:     "open range",
+			q:        CandleQuery{Symbol: "NIFTY", Interval: "1m"},
+			wantCond: []string{"symbol = ANY($1)", "interval = $2"},
+		},
+		{
+			name:     "unix ms bounds",
+			q:        CandleQuery{Symbol: "NIFTY", Interval: "1m", From: 1_700_000_000_000, To: 1_700_000_060_000},
+			wantCond: []string{"start_ts >= $2", "start_ts < $3", "interval = $4"},
+			wantArgs: []any{int64(1_700_000_000_000), int64(1_700_000_060_000)},
+		},
+		{
+			name:     "to without from",
+			q:        CandleQuery{Symbol: "NIFTY", Interval: "1m", To: 1_700_000_060_000},
+			wantCond: []string{"start_ts < $2", "interval = $3"},
+			wantArgs: []any{int64(1_700_000_060_000)},
+		},
+		{
+			name:     "cursor past from",
+			q:        CandleQuery{Symbol: "NIFTY", Interval: "1m", From: 1000, After: 5000},
+			wantCond: []string{"start_ts >= $2"},
+			wantArgs: []any{int64(5001)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := tt.q.where()
+			if strings.Contains(where, "= 0") {
+				t.Errorf("where %q compares a bound against a zero literal", where)
+			}
+			for _, cond := range tt.wantCond {
+				if !strings.Contains(where, cond) {
+					t.Errorf("where %q missing %q", where, cond)
+				}
+			}
+			if tt.q.To == 0 && strings.Contains(where, "start_ts <") {
+				t.Errorf("where %q bounds start_ts above with no To", where)
+			}
+			for i, want := range tt.wantArgs {
+				if got := args[i+1]; got != want {
+					t.Errorf("arg %d = %v (%T), want %v (%T)", i+2, got, got, want, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"ws_ingestor/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// Rejected is a tick the database refused, with the error it gave. Index is
+// its position in the batch passed to InsertBatch.
+type Rejected struct {
+	Record models.MarketData
+	Index  int
+	Err    error
+}
+
+// rowLevel reports whether err is caused by the data of a row rather than
+// by the connection or the server: data exceptions, integrity constraint
+// violations and program limits such as oversized index rows.
+func rowLevel(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Class() {
+	case "22", "23", "54":
+		return true
+	}
+	return false
+}
+
+// ErrorClass names the SQLSTATE class of a Postgres error, e.g.
+// "data_exception", or "other" for errors that did not come from Postgres.
+func ErrorClass(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if name := pqErr.Code.Class().Name(); name != "" {
+			return name
+		}
+		return string(pqErr.Code.Class())
+	}
+	return "other"
+}
+
+// writeIsolated writes rows under a savepoint. When the write fails on a
+// row-level error the savepoint is rolled back and each half is retried, down
+// to single rows, which are rejected. A failure of any other kind is returned
+// and leaves the transaction aborted. base is the position of rows[0] in the
+// slice being written, so rejected rows carry their index.
+func (s *Store) writeIsolated(ctx context.Context, tx *sql.Tx, rows []models.MarketData, base int) (int64, []Rejected, error) {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT insert_batch`); err != nil {
+		return 0, nil, err
+	}
+	inserted, err := s.write(ctx, tx, rows)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT insert_batch`)
+		return inserted, nil, err
+	}
+	if !rowLevel(err) {
+		return 0, nil, err
+	}
+	if _, rerr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT insert_batch`); rerr != nil {
+		return 0, nil, rerr
+	}
+	if len(rows) == 1 {
+		s.logger.Warn(fmt.Sprintf("Rejected %s at %d: %v", rows[0].Name, rows[0].Timestamp, err))
+		_, rerr := tx.ExecContext(ctx, `RELEASE SAVEPOINT insert_batch`)
+		return 0, []Rejected{{Record: rows[0], Index: base, Err: err}}, rerr
+	}
+
+	mid := len(rows) / 2
+	left, leftRejected, err := s.writeIsolated(ctx, tx, rows[:mid], base)
+	if err != nil {
+		return 0, nil, err
+	}
+	right, rightRejected, err := s.writeIsolated(ctx, tx, rows[mid:], base+mid)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT insert_batch`); err != nil {
+		return 0, nil, err
+	}
+	return left + right, append(leftRejected, rightRejected...), nil
+}
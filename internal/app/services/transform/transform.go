@@ -0,0 +1,157 @@
+// Package transform shapes ticks and bars into the flat records clients
+// receive and applies their per-symbol transform profiles, so every output
+// (/ws frames, REST, exports) looks the same.
+package transform
+
+import (
+	"maps"
+	"time"
+
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/instruments"
+)
+
+// Tick flattens a tick: the vendor payload with the typed views on top.
+func Tick(item models.MarketData) dto.FlatMarketData {
+	out := dto.FlatMarketData{}
+
+	// Flatten data block
+	if inner, ok := item.Data["data"].(map[string]interface{}); ok {
+		maps.Copy(out, inner)
+	}
+	// Typed views take precedence over vendor-specific field names
+	if q := item.Quote; q != nil {
+		out["bid"] = q.Bid
+		out["ask"] = q.Ask
+		if q.BidSize != nil {
+			out["bid_size"] = *q.BidSize
+		}
+		if q.AskSize != nil {
+			out["ask_size"] = *q.AskSize
+		}
+	}
+	if t := item.Trade; t != nil {
+		out["price"] = t.Price
+		if t.Qty != nil {
+			out["qty"] = *t.Qty
+		}
+		if t.Side != "" {
+			out["side"] = t.Side
+		}
+	}
+	if b := item.Bar; b != nil {
+		out["open"] = b.Open
+		out["high"] = b.High
+		out["low"] = b.Low
+		out["close"] = b.Close
+		if b.Volume != nil {
+			out["volume"] = *b.Volume
+		}
+		if b.Interval != "" {
+			out["interval"] = b.Interval
+		}
+	}
+	if d := item.Depth; d != nil {
+		out["bids"] = d.Bids
+		out["asks"] = d.Asks
+	}
+	if item.Kind != "" {
+		out["kind"] = item.Kind
+	}
+
+	out["symbol"] = item.Name
+	out["timestamp"] = item.Timestamp
+	out["exchange"] = item.Exchange
+	out["feed"] = item.Feed
+
+	return out
+}
+
+// Apply applies one symbol's profile: value rules, renames, removals and
+// overrides, in that order.
+func Apply(data dto.FlatMarketData, cfg *dto.SymbolConfig, spec instruments.Spec) dto.FlatMarketData {
+
+	// Value transforms
+	for field, rule := range cfg.ValueRules {
+		if v, ok := models.DecimalFrom(data[field]); ok {
+			data[field] = applyValueRule(v, rule, spec.Precision)
+		}
+	}
+
+	// Rename fields
+	for oldKey, newKey := range cfg.RenameFields {
+		if v, ok := data[oldKey]; ok {
+			data[newKey] = v
+			delete(data, oldKey)
+		}
+	}
+
+	// Remove fields
+	for _, field := range cfg.RemoveFields {
+		delete(data, field)
+	}
+
+	// Hard overrides
+	for k, v := range cfg.OverrideFields {
+		if k == "timestamp" && v == "current" {
+			data[k] = time.Now().UnixMilli()
+		} else {
+			data[k] = v
+		}
+	}
+
+	return data
+}
+
+// Bar flattens a bar.
+func Bar(c *models.Candle) dto.FlatMarketData {
+	flat := dto.FlatMarketData{
+		"type":     "bar",
+		"symbol":   c.Symbol,
+		"exchange": c.Exchange,
+		"interval": c.Interval,
+		"start":    c.Start,
+		"end":      c.End,
+		"open":     c.Open,
+		"high":     c.High,
+		"low":      c.Low,
+		"close":    c.Close,
+		"ticks":    c.Ticks,
+		"final":    c.Final,
+	}
+	if c.Volume != nil {
+		flat["volume"] = *c.Volume
+	}
+	return flat
+}
+
+// ForClient applies cfg's profile for symbol, if it has one. catalog
+// supplies the instrument precision value rules round to.
+func ForClient(flat dto.FlatMarketData, symbol, exchange string, cfg *dto.ClientConfig, catalog *instruments.Catalog) dto.FlatMarketData {
+	if cfg == nil || cfg.Symbols == nil {
+		return flat
+	}
+	if sc, ok := cfg.Symbols[symbol]; ok {
+		flat = Apply(flat, &sc, catalog.Spec(symbol, exchange))
+	}
+	return flat
+}
+
+// applyValueRule applies rule in fixed point and rounds the result to the
+// instrument's precision.
+func applyValueRule(num models.Decimal, rule dto.ValueRule, precision int) models.Decimal {
+	switch rule.Op {
+	case "add":
+		return num.Add(rule.Value).Round(precision)
+	case "subtract":
+		return num.Sub(rule.Value).Round(precision)
+	case "multiply":
+		return num.Mul(rule.Value).Round(precision)
+	case "divide":
+		if q, ok := num.Div(rule.Value, precision); ok {
+			return q
+		}
+	}
+	return num
+}
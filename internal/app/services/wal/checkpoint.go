@@ -0,0 +1,115 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ws_ingestor/internal/app/metrics"
+)
+
+// Ack marks offsets as persisted. The checkpoint advances over the
+// contiguous acknowledged prefix; offset 0 (not logged) is ignored.
+func (l *Log) Ack(offsets ...int64) {
+	l.ackMu.Lock()
+	defer l.ackMu.Unlock()
+	for _, o := range offsets {
+		if o >= l.committed {
+			l.acked[o] = struct{}{}
+		}
+	}
+	for {
+		if _, ok := l.acked[l.committed]; !ok {
+			break
+		}
+		delete(l.acked, l.committed)
+		l.committed++
+	}
+}
+
+// Committed returns the first offset not yet acknowledged.
+func (l *Log) Committed() int64 {
+	l.ackMu.Lock()
+	defer l.ackMu.Unlock()
+	return l.committed
+}
+
+func readCheckpoint(path string) (int64, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid wal checkpoint %q", raw)
+	}
+	return n, nil
+}
+
+// saveCheckpoint atomically replaces the checkpoint file when it moved.
+func (l *Log) saveCheckpoint() error {
+	committed := l.Committed()
+	if committed == l.saved {
+		return nil
+	}
+	path := filepath.Join(l.opts.Dir, checkpointFile)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strconv.FormatInt(committed, 10)); err != nil {
+		f.Close()
+		return err
+	}
+	if l.opts.Sync != SyncNone {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	f.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	l.saved = committed
+	return nil
+}
+
+// prune deletes sealed segments whose records are all checkpointed and that
+// are older than the retention period.
+func (l *Log) prune(now time.Time) {
+	committed := l.Committed()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	keep := l.segments[:0]
+	for i, seg := range l.segments {
+		sealed := i < len(l.segments)-1
+		if sealed && l.segments[i+1].base <= committed && l.expired(seg, now) {
+			if err := os.Remove(seg.path); err == nil || errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		keep = append(keep, seg)
+	}
+	l.segments = keep
+
+	metrics.WALSegments.Set(float64(len(l.segments)))
+	metrics.WALPending.Set(float64(l.next - committed))
+}
+
+func (l *Log) expired(seg segment, now time.Time) bool {
+	if l.opts.Retention <= 0 {
+		return true
+	}
+	info, err := os.Stat(seg.path)
+	return err == nil && now.Sub(info.ModTime()) >= l.opts.Retention
+}
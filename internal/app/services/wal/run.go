@@ -0,0 +1,213 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/metrics"
+	"ws_ingestor/internal/app/models"
+)
+
+// Run appends every tick from in to the log and, independently, tails the
+// log from the checkpoint into out. Ticks still unacknowledged from a
+// previous run are replayed first. Run returns once ctx is done and its
+// goroutines have stopped; the log stays open so that consumers can still
+// Ack what they persist while shutting down, and the caller Closes it last.
+func (l *Log) Run(ctx context.Context, in <-chan models.MarketData, out chan<- models.MarketData) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		l.tail(ctx, out)
+	}()
+	go func() {
+		defer wg.Done()
+		l.maintain(ctx)
+	}()
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-in:
+			if _, err := l.Append(m); err != nil {
+				// Not durable, but better delivered than lost
+				l.logger.Error(fmt.Sprintf("WAL append failed, passing %s through: %v", m.Name, err))
+				metrics.ErrorsTotal.WithLabelValues("wal_append").Inc()
+				select {
+				case out <- m:
+				case <-ctx.Done():
+				}
+			}
+		}
+	}
+}
+
+// maintain fsyncs on the interval policy, saves the checkpoint and prunes
+// old segments.
+func (l *Log) maintain(ctx context.Context) {
+	interval := time.Second
+	if l.opts.Sync == SyncInterval && l.opts.SyncInterval > 0 && l.opts.SyncInterval < interval {
+		interval = l.opts.SyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if l.opts.Sync == SyncInterval {
+				if err := l.sync(); err != nil {
+					l.logger.Error(fmt.Sprintf("WAL fsync failed: %v", err))
+					metrics.ErrorsTotal.WithLabelValues("wal_sync").Inc()
+				}
+			}
+			if err := l.saveCheckpoint(); err != nil {
+				l.logger.Error(fmt.Sprintf("Failed to save wal checkpoint: %v", err))
+				metrics.ErrorsTotal.WithLabelValues("wal_checkpoint").Inc()
+			}
+			l.prune(now)
+		}
+	}
+}
+
+// tail delivers records from the checkpoint onwards, following the active
+// segment as it grows.
+func (l *Log) tail(ctx context.Context, out chan<- models.MarketData) {
+	offset := l.Committed()
+
+	var (
+		f   *os.File
+		seg segment
+		pos int64
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for ctx.Err() == nil {
+		if f == nil {
+			var ok bool
+			if seg, ok = l.locate(offset); !ok {
+				if !l.wait(ctx) {
+					return
+				}
+				continue
+			}
+			var err error
+			if f, err = os.Open(seg.path); err != nil {
+				l.logger.Error(fmt.Sprintf("Failed to open wal segment %s: %v", seg.path, err))
+				if retrySleep(ctx) != nil {
+					return
+				}
+				continue
+			}
+			// Skip records before offset within the segment
+			pos = 0
+			for o := seg.base; o < offset; o++ {
+				_, n, err := readRecord(f, pos)
+				if err != nil {
+					break
+				}
+				pos += n
+			}
+		}
+
+		limit, sealed, next := l.bounds(seg)
+		if !sealed && pos >= limit {
+			if !l.wait(ctx) {
+				return
+			}
+			continue
+		}
+
+		m, n, err := readRecord(f, pos)
+		if err != nil {
+			if sealed {
+				// A sealed segment ends here; anything unreadable is lost,
+				// so acknowledge it to keep the checkpoint moving.
+				if offset < next {
+					l.logger.Error(fmt.Sprintf("WAL segment %s unreadable from offset %d: %v", seg.path, offset, err))
+					metrics.ErrorsTotal.WithLabelValues("wal_read").Inc()
+					for o := offset; o < next; o++ {
+						l.Ack(o)
+					}
+				}
+				offset = next
+				f.Close()
+				f = nil
+				continue
+			}
+			if !l.wait(ctx) {
+				return
+			}
+			continue
+		}
+		pos += n
+		m.Offset = offset
+		offset++
+		select {
+		case out <- m:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// locate returns the segment holding offset once that offset is written.
+func (l *Log) locate(offset int64) (segment, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if offset >= l.next {
+		return segment{}, false
+	}
+	for i := len(l.segments) - 1; i >= 0; i-- {
+		if l.segments[i].base <= offset {
+			return l.segments[i], true
+		}
+	}
+	return segment{}, false
+}
+
+// bounds reports how far seg may be read: the written size of the active
+// segment, or sealed with the base of the following segment.
+func (l *Log) bounds(seg segment) (limit int64, sealed bool, following int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, s := range l.segments {
+		if s.base == seg.base {
+			if i == len(l.segments)-1 {
+				return l.activeSize, false, -1
+			}
+			return 0, true, l.segments[i+1].base
+		}
+	}
+	return 0, true, seg.base
+}
+
+// wait blocks until a new record is appended or a second passes.
+func (l *Log) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-l.notify:
+	case <-time.After(time.Second):
+	}
+	return true
+}
+
+func retrySleep(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Second):
+		return nil
+	}
+}
@@ -0,0 +1,262 @@
+package wal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/common/logger"
+	"ws_ingestor/internal/app/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SyncPolicy controls when appended records are fsynced.
+type SyncPolicy string
+
+const (
+	SyncAlways   SyncPolicy = "always"
+	SyncInterval SyncPolicy = "interval"
+	SyncNone     SyncPolicy = "none"
+)
+
+const (
+	segmentExt     = ".wal"
+	checkpointFile = "checkpoint"
+	headerSize     = 8 // length + crc32
+	maxRecord      = 16 << 20
+)
+
+type Options struct {
+	Dir          string
+	SegmentBytes int64
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// Retention keeps fully checkpointed segments around for this long.
+	Retention time.Duration
+}
+
+type segment struct {
+	base int64 // offset of the first record
+	path string
+}
+
+// Log is an append-only, segmented write-ahead log of ticks. Every record
+// gets a monotonically increasing offset starting at 1; consumers Ack
+// offsets once the tick is persisted and the log checkpoints the highest
+// contiguous acknowledged offset.
+type Log struct {
+	opts   Options
+	logger *logrus.Logger
+
+	mu         sync.Mutex
+	segments   []segment
+	active     *os.File
+	activeSize int64
+	next       int64
+	dirty      bool
+	notify     chan struct{}
+
+	ackMu     sync.Mutex
+	committed int64 // every offset below committed is acknowledged
+	acked     map[int64]struct{}
+	saved     int64
+}
+
+// Open opens or creates the log in opts.Dir, truncating a torn record at the
+// end of the last segment.
+func Open(opts Options) (*Log, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	l := &Log{
+		opts:   opts,
+		logger: logger.GetLogger(),
+		notify: make(chan struct{}, 1),
+		acked:  make(map[int64]struct{}),
+	}
+
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		l.segments = append(l.segments, segment{base: base, path: filepath.Join(opts.Dir, name)})
+	}
+	sort.Slice(l.segments, func(i, j int) bool { return l.segments[i].base < l.segments[j].base })
+
+	l.committed, err = readCheckpoint(filepath.Join(opts.Dir, checkpointFile))
+	if err != nil {
+		return nil, err
+	}
+	l.saved = l.committed
+
+	if len(l.segments) == 0 {
+		l.next = l.committed
+		if err := l.roll(); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	// Recover the last segment: count its records and cut a torn tail
+	last := l.segments[len(l.segments)-1]
+	f, err := os.OpenFile(last.path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	var pos, count int64
+	for {
+		_, n, err := readRecord(f, pos)
+		if err != nil {
+			break
+		}
+		pos += n
+		count++
+	}
+	if err := f.Truncate(pos); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.active, l.activeSize, l.next = f, pos, last.base+count
+	if l.committed > l.next {
+		l.committed = l.next
+	}
+	if l.committed < l.segments[0].base {
+		l.committed = l.segments[0].base
+	}
+	if replay := l.next - l.committed; replay > 0 {
+		l.logger.Info(fmt.Sprintf("Replaying %d unacknowledged WAL records from offset %d", replay, l.committed))
+	}
+	return l, nil
+}
+
+// Append writes m to the active segment and returns its offset.
+func (l *Log) Append(m models.MarketData) (int64, error) {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.activeSize > 0 && l.activeSize+int64(len(buf)) > l.opts.SegmentBytes {
+		if err := l.roll(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := l.active.Write(buf); err != nil {
+		return 0, err
+	}
+	if l.opts.Sync == SyncAlways {
+		if err := l.active.Sync(); err != nil {
+			return 0, err
+		}
+	} else {
+		l.dirty = true
+	}
+	l.activeSize += int64(len(buf))
+	offset := l.next
+	l.next++
+
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+	return offset, nil
+}
+
+// roll seals the active segment and starts a new one at l.next. Callers
+// hold l.mu (or own l exclusively).
+func (l *Log) roll() error {
+	if l.next == 0 {
+		l.next = 1
+	}
+	if l.active != nil {
+		if err := l.active.Sync(); err != nil {
+			return err
+		}
+		l.active.Close()
+	}
+	path := filepath.Join(l.opts.Dir, fmt.Sprintf("%020d%s", l.next, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, segment{base: l.next, path: path})
+	l.active, l.activeSize, l.dirty = f, 0, false
+	return nil
+}
+
+func (l *Log) sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.dirty {
+		return nil
+	}
+	l.dirty = false
+	return l.active.Sync()
+}
+
+// Close syncs the active segment and saves the checkpoint.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	err := l.active.Sync()
+	l.active.Close()
+	l.mu.Unlock()
+	if cerr := l.saveCheckpoint(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readRecord reads the record at pos and returns it with its encoded size.
+func readRecord(r io.ReaderAt, pos int64) (models.MarketData, int64, error) {
+	var m models.MarketData
+	var header [headerSize]byte
+	if _, err := r.ReadAt(header[:], pos); err != nil {
+		return m, 0, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecord {
+		return m, 0, fmt.Errorf("record too large at %d", pos)
+	}
+	payload := make([]byte, size)
+	if _, err := r.ReadAt(payload, pos+headerSize); err != nil {
+		return m, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return m, 0, fmt.Errorf("checksum mismatch at %d", pos)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(payload)))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return m, 0, err
+	}
+	return m, headerSize + int64(size), nil
+}
@@ -0,0 +1,243 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"ws_ingestor/internal/app/models"
+)
+
+func openLog(t *testing.T, dir string, segmentBytes int64) *Log {
+	t.Helper()
+	l, err := Open(Options{Dir: dir, SegmentBytes: segmentBytes, Sync: SyncNone})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return l
+}
+
+func appendTicks(t *testing.T, l *Log, names ...string) {
+	t.Helper()
+	for i, name := range names {
+		if _, err := l.Append(models.MarketData{Name: name, Timestamp: int64(1000 + i)}); err != nil {
+			t.Fatalf("append %s: %v", name, err)
+		}
+	}
+}
+
+// lastSegment returns the path of the newest segment in dir.
+func lastSegment(t *testing.T, dir string) string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no segments in %s: %v", dir, err)
+	}
+	sort.Strings(paths)
+	return paths[len(paths)-1]
+}
+
+func TestOpenTruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	l := openLog(t, dir, 1<<20)
+	appendTicks(t, l, "A", "B", "C")
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	path := lastSegment(t, dir)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A header promising 100 bytes followed by only a few of them, as left
+	// by a crash mid-write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 100, 1, 2, 3, 4, '{', '"'})
+	f.Close()
+
+	l = openLog(t, dir, 1<<20)
+	defer l.Close()
+	if after, _ := os.Stat(path); after.Size() != info.Size() {
+		t.Errorf("segment size after recovery = %d, want %d", after.Size(), info.Size())
+	}
+	offset, err := l.Append(models.MarketData{Name: "D", Timestamp: 2000})
+	if err != nil {
+		t.Fatalf("append after recovery: %v", err)
+	}
+	if offset != 4 {
+		t.Errorf("offset after recovery = %d, want 4", offset)
+	}
+}
+
+func TestOpenDropsRecordsFromChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	l := openLog(t, dir, 1<<20)
+	appendTicks(t, l, "A", "B", "C")
+	l.Close()
+
+	path := lastSegment(t, dir)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, n1, err := readRecord(bytes.NewReader(raw), 0)
+	if err != nil {
+		t.Fatalf("read first record: %v", err)
+	}
+	_, n2, err := readRecord(bytes.NewReader(raw), n1)
+	if err != nil {
+		t.Fatalf("read second record: %v", err)
+	}
+	// Corrupt the payload of the second record
+	raw[n1+headerSize+2] ^= 0xff
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readRecord(bytes.NewReader(raw), n1); err == nil {
+		t.Fatal("readRecord accepted a corrupted payload")
+	}
+
+	l = openLog(t, dir, 1<<20)
+	defer l.Close()
+	if info, _ := os.Stat(path); info.Size() != n1 {
+		t.Errorf("segment size after recovery = %d, want %d (second record is %d bytes)", info.Size(), n1, n2)
+	}
+	offset, err := l.Append(models.MarketData{Name: "D"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 2 {
+		t.Errorf("offset after recovery = %d, want 2", offset)
+	}
+}
+
+func TestAckCheckpointsContiguousPrefix(t *testing.T) {
+	l := openLog(t, t.TempDir(), 1<<20)
+	defer l.Close()
+	appendTicks(t, l, "A", "B", "C", "D", "E")
+
+	steps := []struct {
+		ack  []int64
+		want int64
+	}{
+		{ack: []int64{0}, want: 1}, // unlogged ticks are ignored
+		{ack: []int64{2, 3}, want: 1},
+		{ack: []int64{1}, want: 4},
+		{ack: []int64{5}, want: 4},
+		{ack: []int64{2}, want: 4}, // already committed
+		{ack: []int64{4}, want: 6},
+	}
+	for i, st := range steps {
+		l.Ack(st.ack...)
+		if got := l.Committed(); got != st.want {
+			t.Errorf("step %d: after Ack(%v) committed = %d, want %d", i, st.ack, got, st.want)
+		}
+	}
+}
+
+func TestRunReplaysUnacknowledgedAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	// Small segments so the replay crosses segment boundaries
+	l := openLog(t, dir, 200)
+	appendTicks(t, l, "A", "B", "C", "D", "E", "F")
+	l.Ack(1, 2, 4)
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt)); len(segs) < 2 {
+		t.Fatalf("got %d segments, want the ticks spread over several", len(segs))
+	}
+
+	l = openLog(t, dir, 200)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	out := make(chan models.MarketData, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Run(ctx, make(chan models.MarketData), out)
+	}()
+	// Let Run stop before the temp dir is removed
+	defer func() {
+		cancel()
+		<-done
+		l.Close()
+	}()
+
+	// Offset 4 was acknowledged but 3 was not, so the checkpoint stopped at 3
+	want := []string{"C", "D", "E", "F"}
+	for i, name := range want {
+		select {
+		case m := <-out:
+			if m.Name != name || m.Offset != int64(3+i) {
+				t.Errorf("replayed #%d = %s at offset %d, want %s at %d", i, m.Name, m.Offset, name, 3+i)
+			}
+		case <-ctx.Done():
+			t.Fatalf("replayed %d of %d records", i, len(want))
+		}
+	}
+	select {
+	case m := <-out:
+		t.Errorf("unexpected record %s at offset %d", m.Name, m.Offset)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAcksAfterRunStopsAreCheckpointed(t *testing.T) {
+	dir := t.TempDir()
+	l := openLog(t, dir, 1<<20)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	in := make(chan models.MarketData)
+	out := make(chan models.MarketData, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Run(ctx, in, out)
+	}()
+
+	var offsets []int64
+	for _, name := range []string{"A", "B", "C"} {
+		in <- models.MarketData{Name: name, Timestamp: 1000}
+		select {
+		case m := <-out:
+			offsets = append(offsets, m.Offset)
+		case <-ctx.Done():
+			t.Fatalf("%s was not delivered", name)
+		}
+	}
+
+	// A consumer flushing during shutdown acknowledges after Run stopped
+	cancel()
+	<-done
+	l.Ack(offsets...)
+	if err := l.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	l = openLog(t, dir, 1<<20)
+	ctx, cancel = context.WithCancel(context.Background())
+	out = make(chan models.MarketData, 10)
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Run(ctx, make(chan models.MarketData), out)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		l.Close()
+	}()
+	select {
+	case m := <-out:
+		t.Errorf("replayed acknowledged record %s at offset %d", m.Name, m.Offset)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type subscriptionRequest struct {
+	Symbols []string `json:"symbols"`
+}
+
+type subscriptionResponse struct {
+	Feed    string   `json:"feed"`
+	Changed []string `json:"changed,omitempty"`
+	Symbols []string `json:"symbols"`
+}
+
+// NewSubscriptionHandler exposes each feed's subscription set over HTTP. The
+// feed is selected with ?feed=<name>; it may be omitted when only one feed is
+// configured.
+//
+//	GET    -> current symbols (all feeds when no feed is given)
+//	POST   {"symbols":[...]} -> subscribe
+//	DELETE {"symbols":[...]} -> unsubscribe
+func NewSubscriptionHandler(reg *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("feed")
+		if name == "" && r.Method == http.MethodGet {
+			all := make([]subscriptionResponse, 0, len(reg.order))
+			for _, n := range reg.Names() {
+				ing, _ := reg.Get(n)
+				all = append(all, subscriptionResponse{Feed: n, Symbols: ing.Symbols()})
+			}
+			writeJSON(w, all)
+			return
+		}
+		if name == "" && len(reg.order) == 1 {
+			name = reg.order[0]
+		}
+		ing, ok := reg.Get(name)
+		if !ok {
+			http.Error(w, "unknown feed", http.StatusNotFound)
+			return
+		}
+
+		var (
+			changed []string
+			err     error
+		)
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodDelete:
+			var req subscriptionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Symbols) == 0 {
+				http.Error(w, "body must be {\"symbols\":[...]}", http.StatusBadRequest)
+				return
+			}
+			if r.Method == http.MethodPost {
+				changed, err = ing.Subscribe(req.Symbols...)
+			} else {
+				changed, err = ing.Unsubscribe(req.Symbols...)
+			}
+			if err != nil {
+				// The set is updated regardless; it is replayed on the next reconnect.
+				http.Error(w, "subscription updated but upstream send failed: "+err.Error(), http.StatusBadGateway)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, subscriptionResponse{Feed: name, Changed: changed, Symbols: ing.Symbols()})
+	}
+}
+
+type healthResponse struct {
+	Status string       `json:"status"`
+	Feeds  []FeedHealth `json:"feeds"`
+}
+
+// NewHealthHandler reports "ok" when every feed is subscribed and fresh,
+// "down" (503) when every feed's circuit is open, and "degraded" otherwise.
+func NewHealthHandler(reg *Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := healthResponse{Status: "ok", Feeds: reg.Health()}
+		down := 0
+		for _, f := range resp.Feeds {
+			if f.State == StateDown.String() {
+				down++
+			}
+			if f.State != StateSubscribed.String() || f.Stale || len(f.StaleSymbols) > 0 {
+				resp.Status = "degraded"
+			}
+		}
+		if down > 0 && down == len(resp.Feeds) {
+			resp.Status = "down"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		writeJSON(w, resp)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
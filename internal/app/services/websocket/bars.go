@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/bars"
+	"ws_ingestor/internal/app/services/storage"
+	"ws_ingestor/internal/app/services/transform"
+
+	"github.com/gorilla/websocket"
+)
+
+// barMessage is a client request such as
+// {"action":"subscribe_bars","symbols":["NIFTY25DECFUT"],"interval":"1m"}.
+type barMessage struct {
+	Action   string   `json:"action"`
+	Symbols  []string `json:"symbols"`
+	Interval string   `json:"interval"`
+}
+
+func (s *Server) handleBars(client *Client, conn *websocket.Conn, msg []byte) {
+	var m barMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return
+	}
+	if _, ok := bars.Lookup(m.Interval); !ok {
+		return
+	}
+	for _, symbol := range m.Symbols {
+		if symbol == "" {
+			continue
+		}
+		if m.Action == "subscribe_bars" {
+			client.watchBars(conn, symbol, m.Interval)
+		} else {
+			client.unwatchBars(conn, symbol, m.Interval)
+		}
+	}
+}
+
+// barWatch is one bar stream of a connection; closed is the start of the
+// last closed bar sent, so each closed bar goes out once.
+type barWatch struct {
+	symbol   string
+	interval string
+	closed   int64
+}
+
+func (w *barWatch) key() string {
+	return w.symbol + "|" + w.interval
+}
+
+// next returns the bars to send for a cached (current, closed) pair.
+func (w *barWatch) next(pair [2]*models.Candle) []*models.Candle {
+	current, closed := pair[0], pair[1]
+	var out []*models.Candle
+	if closed != nil && closed.Start > w.closed {
+		out = append(out, closed)
+		w.closed = closed.Start
+	}
+	if current != nil && current.Start > w.closed {
+		out = append(out, current)
+	}
+	return out
+}
+
+// barSnapshots reads each symbol's bars once per broadcast.
+type barSnapshots struct {
+	ctx   context.Context
+	cache *storage.CacheService
+	seen  map[string][2]*models.Candle
+}
+
+func (b *barSnapshots) get(symbol, interval string) [2]*models.Candle {
+	k := symbol + "|" + interval
+	if pair, ok := b.seen[k]; ok {
+		return pair
+	}
+	var pair [2]*models.Candle
+	if current, closed, err := b.cache.GetCandles(b.ctx, symbol, interval); err == nil {
+		pair = [2]*models.Candle{current, closed}
+	}
+	b.seen[k] = pair
+	return pair
+}
+
+// barFrame flattens a bar with the client's transform for its symbol.
+func (s *Server) barFrame(c *models.Candle, cfg *dto.ClientConfig) dto.FlatMarketData {
+	return transform.ForClient(transform.Bar(c), c.Symbol, c.Exchange, cfg, s.catalog)
+}
@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/options"
+	"ws_ingestor/internal/app/services/transform"
+
+	"github.com/gorilla/websocket"
+)
+
+// chainMessage is a client request such as
+// {"action":"subscribe_chain","underlying":"NIFTY","expiry":"nearest","strikes":5}.
+type chainMessage struct {
+	Action string `json:"action"`
+	options.Request
+}
+
+func (s *Server) handleMessage(client *Client, conn *websocket.Conn, msg []byte) {
+	var head struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(msg, &head); err != nil {
+		return
+	}
+	switch head.Action {
+	case "subscribe_chain", "unsubscribe_chain":
+		s.handleChain(client, conn, msg)
+	case "subscribe_bars", "unsubscribe_bars":
+		s.handleBars(client, conn, msg)
+	}
+}
+
+func (s *Server) handleChain(client *Client, conn *websocket.Conn, msg []byte) {
+	if s.chains == nil {
+		return
+	}
+	var m chainMessage
+	if err := json.Unmarshal(msg, &m); err != nil || m.Underlying == "" {
+		return
+	}
+	switch m.Action {
+	case "subscribe_chain":
+		if client.watchChain(conn, m.Request) {
+			s.chains.Watch(m.Request)
+		}
+	case "unsubscribe_chain":
+		if client.unwatchChain(conn, m.Request) {
+			s.chains.Unwatch(m.Request)
+		}
+	}
+}
+
+// chainSnapshots resolves each chain once per broadcast, however many
+// connections watch it.
+type chainSnapshots struct {
+	ctx    context.Context
+	chains *options.Service
+	now    time.Time
+	cache  map[string]*options.Chain
+}
+
+func (c *chainSnapshots) get(req options.Request) *options.Chain {
+	if chain, ok := c.cache[req.Key()]; ok {
+		return chain
+	}
+	var out *options.Chain
+	if chain, err := c.chains.Snapshot(c.ctx, req, c.now); err == nil {
+		out = &chain
+	}
+	c.cache[req.Key()] = out
+	return out
+}
+
+// chainFrame flattens a chain snapshot with the client's per-symbol
+// transforms applied to every leg.
+func (s *Server) chainFrame(chain *options.Chain, cfg *dto.ClientConfig) map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(chain.Rows))
+	for _, r := range chain.Rows {
+		row := map[string]interface{}{"strike": r.Strike, "call": r.Call, "put": r.Put}
+		if r.CE != nil {
+			row["ce"] = s.flatten(*r.CE, cfg)
+		}
+		if r.PE != nil {
+			row["pe"] = s.flatten(*r.PE, cfg)
+		}
+		rows = append(rows, row)
+	}
+	frame := map[string]interface{}{
+		"type":       "chain",
+		"underlying": chain.Underlying,
+		"expiry":     chain.Expiry,
+		"expiries":   chain.Expiries,
+		"atm":        chain.ATM,
+		"strikes":    rows,
+	}
+	if chain.Spot != nil {
+		frame["spot"] = *chain.Spot
+	}
+	return frame
+}
+
+// flatten normalises a tick and applies the client's transform for it.
+func (s *Server) flatten(item models.MarketData, cfg *dto.ClientConfig) dto.FlatMarketData {
+	return transform.ForClient(transform.Tick(item), item.Name, item.Exchange, cfg, s.catalog)
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"sync"
 	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/services/options"
 
 	"github.com/gorilla/websocket"
 )
@@ -10,6 +11,8 @@ import (
 type Client struct {
 	ID     string
 	conns  map[*websocket.Conn]struct{}
+	chains map[*websocket.Conn]map[string]options.Request
+	bars   map[*websocket.Conn]map[string]*barWatch
 	mu     sync.Mutex
 	Config *dto.ClientConfig
 }
@@ -20,10 +23,61 @@ func (c *Client) addConn(conn *websocket.Conn) {
 	c.conns[conn] = struct{}{}
 }
 
-func (c *Client) removeConn(conn *websocket.Conn) {
+// removeConn drops conn and returns the chains it was watching.
+func (c *Client) removeConn(conn *websocket.Conn) []options.Request {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.conns, conn)
+	reqs := make([]options.Request, 0, len(c.chains[conn]))
+	for _, req := range c.chains[conn] {
+		reqs = append(reqs, req)
+	}
+	delete(c.chains, conn)
+	delete(c.bars, conn)
+	return reqs
+}
+
+// watchChain adds req to conn's chains; it reports false if already watched.
+func (c *Client) watchChain(conn *websocket.Conn, req options.Request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.chains[conn] == nil {
+		c.chains[conn] = make(map[string]options.Request)
+	}
+	if _, ok := c.chains[conn][req.Key()]; ok {
+		return false
+	}
+	c.chains[conn][req.Key()] = req
+	return true
+}
+
+func (c *Client) unwatchChain(conn *websocket.Conn, req options.Request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.chains[conn][req.Key()]; !ok {
+		return false
+	}
+	delete(c.chains[conn], req.Key())
+	return true
+}
+
+// watchBars subscribes conn to the bars of symbol for interval.
+func (c *Client) watchBars(conn *websocket.Conn, symbol, interval string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bars[conn] == nil {
+		c.bars[conn] = make(map[string]*barWatch)
+	}
+	w := &barWatch{symbol: symbol, interval: interval}
+	if _, ok := c.bars[conn][w.key()]; !ok {
+		c.bars[conn][w.key()] = w
+	}
+}
+
+func (c *Client) unwatchBars(conn *websocket.Conn, symbol, interval string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.bars[conn], (&barWatch{symbol: symbol, interval: interval}).key())
 }
 
 func (c *Client) isEmpty() bool {
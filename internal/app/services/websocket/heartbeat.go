@@ -0,0 +1,233 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ws_ingestor/internal/app/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// FeedHealth is the health snapshot reported for one feed.
+type FeedHealth struct {
+	Feed         string    `json:"feed"`
+	State        string    `json:"state"`
+	Connected    bool      `json:"connected"`
+	Stale        bool      `json:"stale"`
+	StaleSymbols []string  `json:"stale_symbols,omitempty"`
+	LastTickAt   time.Time `json:"last_tick_at,omitzero"`
+	Stalls       uint64    `json:"stalls"`
+}
+
+// liveness tracks tick arrival for the feed and each symbol.
+type liveness struct {
+	mu           sync.Mutex
+	connectedAt  time.Time
+	lastTick     time.Time
+	lastSeen     map[string]time.Time
+	stale        bool
+	staleSymbols map[string]struct{}
+	stalls       uint64
+}
+
+func newLiveness() *liveness {
+	return &liveness{
+		lastSeen:     make(map[string]time.Time),
+		staleSymbols: make(map[string]struct{}),
+	}
+}
+
+func (l *liveness) connected(now time.Time) {
+	l.mu.Lock()
+	l.connectedAt = now
+	l.mu.Unlock()
+}
+
+func (c *Ingestor) touch(symbol string, now time.Time) {
+	l := c.live
+	l.mu.Lock()
+	l.lastTick = now
+	l.lastSeen[symbol] = now
+	if l.stale {
+		l.stale = false
+		metrics.FeedStale.WithLabelValues(c.name).Set(0)
+	}
+	if _, ok := l.staleSymbols[symbol]; ok {
+		delete(l.staleSymbols, symbol)
+		metrics.SymbolStale.WithLabelValues(c.name, symbol).Set(0)
+	}
+	l.mu.Unlock()
+}
+
+// forget drops tracking for unsubscribed symbols.
+func (c *Ingestor) forget(symbols []string) {
+	l := c.live
+	l.mu.Lock()
+	for _, s := range symbols {
+		delete(l.lastSeen, s)
+		delete(l.staleSymbols, s)
+		metrics.SymbolStale.DeleteLabelValues(c.name, s)
+	}
+	l.mu.Unlock()
+}
+
+// Health reports connection and staleness state for the feed.
+func (c *Ingestor) Health() FeedHealth {
+	c.mu.RLock()
+	connected := c.conn != nil
+	state := c.state
+	c.mu.RUnlock()
+
+	l := c.live
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := FeedHealth{
+		Feed:       c.name,
+		State:      state.String(),
+		Connected:  connected,
+		Stale:      l.stale,
+		LastTickAt: l.lastTick,
+		Stalls:     l.stalls,
+	}
+	for s := range l.staleSymbols {
+		h.StaleSymbols = append(h.StaleSymbols, s)
+	}
+	sort.Strings(h.StaleSymbols)
+	return h
+}
+
+// armReadDeadline sets the initial read deadline and extends it on every
+// pong. It must run before readLoop starts reading conn.
+func (c *Ingestor) armReadDeadline(conn *websocket.Conn) {
+	if c.readTimeout <= 0 {
+		return
+	}
+	conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	})
+}
+
+// keepalive pings the upstream and runs the stale watchdog until ctx is
+// done. Closing conn makes readLoop return, which triggers a reconnect in
+// Start.
+func (c *Ingestor) keepalive(ctx context.Context, conn *websocket.Conn) {
+	var pingC <-chan time.Time
+	if c.pingInterval > 0 {
+		t := time.NewTicker(c.pingInterval)
+		defer t.Stop()
+		pingC = t.C
+	}
+
+	var watchC <-chan time.Time
+	if c.staleAfter > 0 || c.symbolStaleAfter > 0 {
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		watchC = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return
+		case <-pingC:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				c.logger.Warn(fmt.Sprintf("Ping failed on feed %s: %v", c.name, err))
+			}
+		case now := <-watchC:
+			if scope, detail := c.checkStale(now); scope != "" {
+				c.logger.Warn(fmt.Sprintf("Feed %s stalled (%s: %s), forcing reconnect", c.name, scope, detail))
+				metrics.FeedStalls.WithLabelValues(c.name, scope).Inc()
+				c.setState(StateDegraded)
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// sessionOpen returns when the trading session of symbol's exchange that
+// contains now opened; ok is false while the exchange is closed. The zero
+// time is returned for exchanges that never close.
+func (c *Ingestor) sessionOpen(symbol string, now time.Time) (open time.Time, ok bool) {
+	if c.calendar == nil {
+		return time.Time{}, true
+	}
+	exch, _ := c.catalog.Exchange(symbol)
+	if c.calendar.AlwaysOpen(exch) {
+		return time.Time{}, true
+	}
+	open, _, ok = c.calendar.SessionBounds(exch, now)
+	return open, ok
+}
+
+// checkStale returns the stall scope ("feed" or "symbol") when the watchdog
+// window has been exceeded. Silence is only counted while a symbol's
+// exchange is open, measured from the session open at the earliest, so
+// nights, weekends and holidays do not trigger reconnects.
+func (c *Ingestor) checkStale(now time.Time) (scope, detail string) {
+	symbols := c.Symbols()
+	opens := make(map[string]time.Time, len(symbols))
+	for _, s := range symbols {
+		if open, ok := c.sessionOpen(s, now); ok {
+			opens[s] = open
+		}
+	}
+
+	l := c.live
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	since := func(t, open time.Time) time.Duration {
+		if t.Before(l.connectedAt) {
+			t = l.connectedAt
+		}
+		if t.Before(open) {
+			t = open
+		}
+		return now.Sub(t)
+	}
+
+	// The feed is expected to tick once any of its exchanges is open; a
+	// feed without subscriptions has nothing to send
+	var (
+		feedOpen time.Time
+		anyOpen  bool
+	)
+	for _, open := range opens {
+		if !anyOpen || open.Before(feedOpen) {
+			feedOpen, anyOpen = open, true
+		}
+	}
+
+	if c.staleAfter > 0 && anyOpen {
+		if idle := since(l.lastTick, feedOpen); idle > c.staleAfter {
+			l.stale = true
+			l.stalls++
+			metrics.FeedStale.WithLabelValues(c.name).Set(1)
+			return "feed", fmt.Sprintf("no ticks for %s", idle.Truncate(time.Second))
+		}
+	}
+
+	if c.symbolStaleAfter > 0 {
+		var stale []string
+		for _, s := range symbols {
+			open, ok := opens[s]
+			if ok && since(l.lastSeen[s], open) > c.symbolStaleAfter {
+				stale = append(stale, s)
+				l.staleSymbols[s] = struct{}{}
+				metrics.SymbolStale.WithLabelValues(c.name, s).Set(1)
+			}
+		}
+		if len(stale) > 0 {
+			l.stalls++
+			return "symbol", fmt.Sprintf("%d symbols silent, first %s", len(stale), stale[0])
+		}
+	}
+	return "", ""
+}
@@ -5,27 +5,106 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"ws_ingestor/internal/app/common/logger"
-	"ws_ingestor/internal/app/constants"
+	"ws_ingestor/internal/app/common/retry"
+	"ws_ingestor/internal/app/config"
 	"ws_ingestor/internal/app/metrics"
 	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/backpressure"
+	"ws_ingestor/internal/app/services/calendar"
+	"ws_ingestor/internal/app/services/deadletter"
+	"ws_ingestor/internal/app/services/decoder"
+	"ws_ingestor/internal/app/services/gaps"
+	"ws_ingestor/internal/app/services/instruments"
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
 type Ingestor struct {
-	url     string
-	apiKey  string
-	out     chan<- models.MarketData
-	symbols []string
-	logger  *logrus.Logger
+	name       string
+	url        string
+	authHeader string
+	apiKey     string
+	decoder    decoder.Decoder
+	catalog    *instruments.Catalog
+	calendar   *calendar.Calendar
+	out        *backpressure.Queue
+	logger     *logrus.Logger
+
+	mu      sync.RWMutex
+	symbols map[string]struct{}
+	conn    *websocket.Conn
+
+	// writeMu serialises writes on conn; gorilla allows one concurrent writer.
+	writeMu sync.Mutex
+
+	pingInterval     time.Duration
+	readTimeout      time.Duration
+	staleAfter       time.Duration
+	symbolStaleAfter time.Duration
+	live             *liveness
+
+	backoffInitial   time.Duration
+	backoffMax       time.Duration
+	backoffJitter    float64
+	minStable        time.Duration
+	failureThreshold int
+	state            ConnState
+
+	gaps *gaps.Tracker
+	dead *deadletter.Writer
 }
 
-func New(url, apiKey string, out chan<- models.MarketData, symbols []string) *Ingestor {
-	return &Ingestor{url: url, apiKey: apiKey, out: out, symbols: symbols, logger: logger.GetLogger()}
+// New builds an Ingestor for feed. filler and recorder are optional and
+// enable gap backfill and gap persistence; dead (optional) receives frames
+// that fail to decode or validate. cal (optional) pauses the stale watchdog
+// while the exchanges of the feed's symbols are closed.
+func New(feed config.FeedConfig, dec decoder.Decoder, out *backpressure.Queue, catalog *instruments.Catalog, cal *calendar.Calendar, filler gaps.GapFiller, recorder gaps.Recorder, dead *deadletter.Writer) *Ingestor {
+	set := make(map[string]struct{}, len(feed.Symbols))
+	for _, s := range feed.Symbols {
+		if s != "" {
+			set[s] = struct{}{}
+		}
+	}
+	c := &Ingestor{
+		name:       feed.Name,
+		url:        feed.URL,
+		authHeader: feed.AuthHeader,
+		apiKey:     feed.APIKey,
+		decoder:    dec,
+		catalog:    catalog,
+		calendar:   cal,
+		out:        out,
+		symbols:    set,
+		logger:     logger.GetLogger(),
+
+		pingInterval:     feed.PingInterval,
+		readTimeout:      feed.ReadTimeout,
+		staleAfter:       feed.StaleAfter,
+		symbolStaleAfter: feed.SymbolStaleAfter,
+		live:             newLiveness(),
+
+		backoffInitial:   feed.BackoffInitial,
+		backoffMax:       feed.BackoffMax,
+		backoffJitter:    feed.BackoffJitter,
+		minStable:        feed.MinStable,
+		failureThreshold: feed.FailureThreshold,
+		dead:             dead,
+	}
+	// Backfilled ticks bypass the overflow policy so a fill is never dropped
+	c.gaps = gaps.NewTracker(feed.Name, feed.GapThreshold, filler, recorder, out.C(), c.enrich)
+	return c
+}
+
+// Name returns the feed this Ingestor consumes.
+func (c *Ingestor) Name() string {
+	return c.name
 }
 
 func (c *Ingestor) Start(ctx context.Context) {
@@ -34,51 +113,175 @@ func (c *Ingestor) Start(ctx context.Context) {
 			c.logger.WithField("panic", r).Error("WebSocket Ingestor panicked")
 		}
 	}()
-	backoff := time.Second
+	backoff := c.newBackoff()
+	failures := 0
+	c.setState(StateConnecting)
 
 	for {
-		select {
-		case <-ctx.Done():
+		if ctx.Err() != nil {
 			return
-		default:
 		}
 
 		header := http.Header{}
-		header.Set("x-api-key", c.apiKey)
+		header.Set(c.authHeader, c.apiKey)
 
-		conn, _, err := websocket.DefaultDialer.Dial(c.url, header)
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.url, header)
 		if err != nil {
-			c.logger.Error(fmt.Sprintf("WS connect failed: %v", err))
-			time.Sleep(backoff)
-			if backoff < 30*time.Second {
-				backoff *= 2
+			failures++
+			if failures >= c.failureThreshold {
+				c.setState(StateDown)
+				backoff.Saturate()
+			} else if c.State() != StateDown {
+				c.setState(StateConnecting)
+			}
+			delay := backoff.Next()
+			c.logger.Error(fmt.Sprintf("WS connect failed for feed %s (attempt %d), retrying in %s: %v", c.name, failures, delay.Round(time.Millisecond), err))
+			if retry.Sleep(ctx, delay) != nil {
+				return
 			}
 			continue
 		}
 
-		c.logger.Info("WebSocket connected")
-		backoff = time.Second
+		c.logger.Info(fmt.Sprintf("WebSocket connected to feed %s", c.name))
+		failures = 0
 
-		// Send subscription message after connecting
-		subscriptionMsg := map[string]interface{}{
-			"event":   "subscribe",
-			"symbols": c.symbols,
-		}
-		msgBytes, err := json.Marshal(subscriptionMsg)
-		if err != nil {
-			c.logger.Error(fmt.Sprintf("Failed to marshal subscription message: %v", err))
-			conn.Close()
-			continue
-		}
-		if err := conn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
+		// Replay the current subscription set on every (re)connect
+		c.mu.Lock()
+		c.conn = conn
+		symbols := c.symbolList()
+		c.mu.Unlock()
+
+		if err := c.sendEvent(conn, "subscribe", symbols); err != nil {
 			c.logger.Error(fmt.Sprintf("Failed to send subscription message: %v", err))
+			c.detach(conn)
 			conn.Close()
+			c.setState(StateDegraded)
+			if retry.Sleep(ctx, backoff.Next()) != nil {
+				return
+			}
 			continue
 		}
-		c.logger.Info("Subscription message sent")
+		c.logger.Info(fmt.Sprintf("Subscription message sent for %d symbols on feed %s", len(symbols), c.name))
+		c.setState(StateSubscribed)
+
+		connectedAt := time.Now()
+		c.live.connected(connectedAt)
+		c.armReadDeadline(conn)
+		connCtx, cancelConn := context.WithCancel(ctx)
+		go c.keepalive(connCtx, conn)
 
 		c.readLoop(ctx, conn)
+		cancelConn()
+		c.detach(conn)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Only a session that stayed up long enough resets the backoff; a
+		// stall flagged by the watchdog keeps the feed degraded until the
+		// next successful subscribe.
+		stable := backoff.ResetIfStable(time.Since(connectedAt))
+		if !stable || c.State() == StateDegraded {
+			c.setState(StateDegraded)
+		} else {
+			c.setState(StateConnecting)
+		}
+		if retry.Sleep(ctx, backoff.Next()) != nil {
+			return
+		}
+	}
+}
+
+func (c *Ingestor) newBackoff() *retry.Backoff {
+	b := retry.NewBackoff(c.backoffInitial, c.backoffMax)
+	b.Jitter = c.backoffJitter
+	b.MinStable = c.minStable
+	return b
+}
+
+// Subscribe adds symbols to the subscription set and, when connected, sends
+// an incremental subscribe frame for the ones not already subscribed.
+func (c *Ingestor) Subscribe(symbols ...string) ([]string, error) {
+	c.mu.Lock()
+	added := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if s == "" {
+			continue
+		}
+		if _, ok := c.symbols[s]; !ok {
+			c.symbols[s] = struct{}{}
+			added = append(added, s)
+		}
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil || len(added) == 0 {
+		return added, nil
 	}
+	return added, c.sendEvent(conn, "subscribe", added)
+}
+
+// Unsubscribe removes symbols from the subscription set and, when connected,
+// sends an incremental unsubscribe frame for the ones that were subscribed.
+func (c *Ingestor) Unsubscribe(symbols ...string) ([]string, error) {
+	c.mu.Lock()
+	removed := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if _, ok := c.symbols[s]; ok {
+			delete(c.symbols, s)
+			removed = append(removed, s)
+		}
+	}
+	conn := c.conn
+	c.mu.Unlock()
+	c.forget(removed)
+
+	if conn == nil || len(removed) == 0 {
+		return removed, nil
+	}
+	return removed, c.sendEvent(conn, "unsubscribe", removed)
+}
+
+// Symbols returns the current subscription set in sorted order.
+func (c *Ingestor) Symbols() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.symbolList()
+}
+
+func (c *Ingestor) symbolList() []string {
+	out := make([]string, 0, len(c.symbols))
+	for s := range c.symbols {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (c *Ingestor) sendEvent(conn *websocket.Conn, event string, symbols []string) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+	msgBytes, err := json.Marshal(map[string]interface{}{
+		"event":   event,
+		"symbols": symbols,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return conn.WriteMessage(websocket.TextMessage, msgBytes)
+}
+
+func (c *Ingestor) detach(conn *websocket.Conn) {
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.mu.Unlock()
 }
 
 func (c *Ingestor) readLoop(ctx context.Context, conn *websocket.Conn) {
@@ -96,32 +299,90 @@ func (c *Ingestor) readLoop(ctx context.Context, conn *websocket.Conn) {
 		default:
 		}
 
-		_, msg, err := conn.ReadMessage()
+		mt, msg, err := conn.ReadMessage()
 		if err != nil {
-			c.logger.Error(fmt.Sprintf("WS read error: %v", err))
+			c.logger.Error(fmt.Sprintf("WS read error on feed %s: %v", c.name, err))
 			return
 		}
+		if c.readTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
 
-		var data models.MarketData
-		if err := json.Unmarshal(msg, &data); err != nil {
+		records, err := c.decoder.Decode(mt, msg)
+		if err != nil {
 			c.logger.Error(fmt.Sprintf("Failed to unmarshal message: %v", err))
 			metrics.ErrorsTotal.WithLabelValues("unmarshal").Inc()
+			c.reject(models.StageDecode, "", err, msg)
 			continue
 		}
-		if err := data.Validate(); err != nil {
-			c.logger.Error(fmt.Sprintf("Invalid market data: %v", err))
-			metrics.ErrorsTotal.WithLabelValues("validation").Inc()
-			continue
+
+		for _, data := range records {
+			if err := data.Validate(); err != nil {
+				c.logger.Error(fmt.Sprintf("Invalid market data: %v", err))
+				metrics.ErrorsTotal.WithLabelValues("validation").Inc()
+				c.reject(models.StageValidate, data.Name, err, msg)
+				continue
+			}
+			c.enrich(&data)
+			c.touch(data.Name, time.Now())
+			c.gaps.Observe(ctx, data)
+
+			metrics.MessagesReceived.WithLabelValues(c.name).Inc()
+			c.out.Put(ctx, data)
 		}
-		// Set exchange based on symbol
-		allSymbols := constants.GetAllSymbols()
-		if exch, ok := allSymbols[data.Name]; ok {
-			data.Exchange = exch
-		} else {
-			data.Exchange = "unknown"
+	}
+}
+
+func (c *Ingestor) reject(stage, symbol string, err error, frame []byte) {
+	c.dead.Put(models.DeadLetter{
+		Feed:       c.name,
+		Symbol:     symbol,
+		Stage:      stage,
+		Reason:     err.Error(),
+		Raw:        append([]byte(nil), frame...),
+		ReceivedAt: time.Now(),
+	})
+}
+
+// Decode runs a frame through the feed's decoder, validation and enrichment
+// without publishing it; dead letters are re-driven through it.
+func (c *Ingestor) Decode(frame []byte) ([]models.MarketData, error) {
+	mt := websocket.TextMessage
+	if !utf8.Valid(frame) {
+		mt = websocket.BinaryMessage
+	}
+	records, err := c.decoder.Decode(mt, frame)
+	if err != nil {
+		return nil, err
+	}
+	out := records[:0]
+	for _, data := range records {
+		if err := data.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", data.Name, err)
 		}
+		c.enrich(&data)
+		out = append(out, data)
+	}
+	return out, nil
+}
+
+// enrich stamps the exchange and source feed on a decoded record and derives
+// its typed and option views.
+func (c *Ingestor) enrich(data *models.MarketData) {
+	data.Classify()
 
-		metrics.MessagesReceived.Inc()
-		c.out <- data
+	// Set exchange from the instrument master, falling back to what the decoder supplied
+	if exch, ok := c.catalog.Exchange(data.Name); ok {
+		data.Exchange = exch
+	}
+	if opt, ok := c.catalog.Option(data.Name); ok {
+		data.Option = &opt
+		if data.Exchange == "" {
+			data.Exchange = "cepe"
+		}
+	}
+	if data.Exchange == "" {
+		data.Exchange = "unknown"
 	}
+	data.Feed = c.name
 }
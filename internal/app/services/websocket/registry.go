@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"ws_ingestor/internal/app/config"
+	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/backpressure"
+	"ws_ingestor/internal/app/services/calendar"
+	"ws_ingestor/internal/app/services/deadletter"
+	"ws_ingestor/internal/app/services/decoder"
+	"ws_ingestor/internal/app/services/gaps"
+	"ws_ingestor/internal/app/services/instruments"
+)
+
+// Registry owns one Ingestor per configured upstream feed. All feeds fan into
+// the same output queue.
+type Registry struct {
+	feeds map[string]*Ingestor
+	order []string
+}
+
+// NewRegistry builds the feeds. catalog resolves exchanges for incoming
+// symbols and cal (optional) their trading hours; recorder (optional)
+// persists detected gaps and dead (optional) receives rejected frames.
+func NewRegistry(feeds []config.FeedConfig, out *backpressure.Queue, catalog *instruments.Catalog, cal *calendar.Calendar, recorder gaps.Recorder, dead *deadletter.Writer) (*Registry, error) {
+	r := &Registry{feeds: make(map[string]*Ingestor, len(feeds))}
+	for _, f := range feeds {
+		dec, err := decoder.New(f)
+		if err != nil {
+			return nil, err
+		}
+		// A custom decoder may backfill on its own; otherwise use the REST filler
+		filler, _ := dec.(gaps.GapFiller)
+		if filler == nil && f.GapFillURL != "" {
+			fillCfg := f
+			fillCfg.Parser = f.GapFillParser
+			fillDec, err := decoder.New(fillCfg)
+			if err != nil {
+				return nil, err
+			}
+			filler = gaps.NewRESTFiller(f.GapFillURL, f.AuthHeader, f.APIKey, fillDec)
+		}
+		r.feeds[f.Name] = New(f, dec, out, catalog, cal, filler, recorder, dead)
+		r.order = append(r.order, f.Name)
+	}
+	return r, nil
+}
+
+// Start runs every feed and blocks until all of them return.
+func (r *Registry) Start(ctx context.Context) {
+	wg := &sync.WaitGroup{}
+	for _, name := range r.order {
+		wg.Add(1)
+		go func(ing *Ingestor) {
+			defer wg.Done()
+			ing.Start(ctx)
+		}(r.feeds[name])
+	}
+	wg.Wait()
+}
+
+func (r *Registry) Get(name string) (*Ingestor, bool) {
+	ing, ok := r.feeds[name]
+	return ing, ok
+}
+
+// Names returns feed names in configuration order.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Health returns the health of every feed in configuration order.
+func (r *Registry) Health() []FeedHealth {
+	out := make([]FeedHealth, 0, len(r.order))
+	for _, name := range r.order {
+		out = append(out, r.feeds[name].Health())
+	}
+	return out
+}
+
+// Decode decodes a frame as feed would; see Ingestor.Decode.
+func (r *Registry) Decode(feed string, frame []byte) ([]models.MarketData, error) {
+	ing, ok := r.feeds[feed]
+	if !ok {
+		return nil, fmt.Errorf("unknown feed %q", feed)
+	}
+	return ing.Decode(frame)
+}
+
+// Subscriptions returns the subscription set of every feed.
+func (r *Registry) Subscriptions() map[string][]string {
+	out := make(map[string][]string, len(r.order))
+	for _, name := range r.order {
+		out[name] = r.feeds[name].Symbols()
+	}
+	return out
+}
+
+// Subscribe adds symbols to one feed.
+func (r *Registry) Subscribe(feed string, symbols ...string) error {
+	ing, ok := r.feeds[feed]
+	if !ok {
+		return fmt.Errorf("unknown feed %q", feed)
+	}
+	_, err := ing.Subscribe(symbols...)
+	return err
+}
+
+// Unsubscribe removes symbols from one feed.
+func (r *Registry) Unsubscribe(feed string, symbols ...string) error {
+	ing, ok := r.feeds[feed]
+	if !ok {
+		return fmt.Errorf("unknown feed %q", feed)
+	}
+	_, err := ing.Unsubscribe(symbols...)
+	return err
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"net/http"
+	"time"
+
+	"ws_ingestor/internal/app/dto"
+
+	"github.com/gorilla/websocket"
+)
+
+// serveReplay connects a client to replay session id. The connection gets
+// every replayed tick as it is played, shaped like live tick frames, and a
+// final {"type":"replay_end"} frame with the session status. It is not
+// registered as a live client, so the broadcaster never writes to it.
+func (s *Server) serveReplay(w http.ResponseWriter, r *http.Request, id string, cfg *dto.ClientConfig) {
+	if s.replays == nil {
+		http.Error(w, "replay is disabled", http.StatusNotFound)
+		return
+	}
+	session, ok := s.replays.Get(id)
+	if !ok {
+		http.Error(w, "unknown replay", http.StatusNotFound)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ticks, leave := session.Subscribe()
+	defer leave()
+
+	// The client sends nothing; reading only notices it going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case m := <-ticks:
+			if err := conn.WriteJSON(s.flatten(m, cfg)); err != nil {
+				return
+			}
+		case <-session.Done():
+			// Everything played was queued before Done; flush it
+			for len(ticks) > 0 {
+				if err := conn.WriteJSON(s.flatten(<-ticks, cfg)); err != nil {
+					return
+				}
+			}
+			conn.WriteJSON(map[string]interface{}{"type": "replay_end", "replay": session.Status()})
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replay ended"), time.Now().Add(5*time.Second))
+			return
+		case <-closed:
+			return
+		}
+	}
+}
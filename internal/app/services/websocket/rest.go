@@ -0,0 +1,263 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ws_ingestor/internal/app/dto"
+	"ws_ingestor/internal/app/services/bars"
+	"ws_ingestor/internal/app/services/export"
+	"ws_ingestor/internal/app/services/storage"
+	"ws_ingestor/internal/utils"
+)
+
+const (
+	defaultPageSize = 1000
+	maxPageSize     = 10000
+	maxLatest       = 500
+)
+
+// page is the body of every /v1 response. NextCursor is passed back as
+// cursor= to fetch the following page; it is omitted on the last one.
+type page struct {
+	Data       []dto.FlatMarketData `json:"data"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// historyQuery holds the parameters shared by /v1/ticks and /v1/bars.
+type historyQuery struct {
+	symbol string
+	from   int64
+	to     int64
+	limit  int
+	cursor string
+}
+
+// parseHistory reads symbol, from, to, limit and cursor. Times are Unix
+// milliseconds or RFC3339.
+func parseHistory(r *http.Request) (historyQuery, error) {
+	q := r.URL.Query()
+	h := historyQuery{symbol: q.Get("symbol"), limit: defaultPageSize, cursor: q.Get("cursor")}
+	if h.symbol == "" {
+		return h, fmt.Errorf("symbol is required")
+	}
+	var err error
+	if h.from, err = utils.ParseMillis(q.Get("from")); err != nil {
+		return h, fmt.Errorf("invalid from: %w", err)
+	}
+	if h.to, err = utils.ParseMillis(q.Get("to")); err != nil {
+		return h, fmt.Errorf("invalid to: %w", err)
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return h, fmt.Errorf("invalid limit %q", v)
+		}
+		h.limit = min(n, maxPageSize)
+	}
+	return h, nil
+}
+
+// Cursors are opaque to clients: dot-separated integers, base64 encoded.
+func encodeCursor(parts ...int64) string {
+	s := make([]string, len(parts))
+	for i, p := range parts {
+		s[i] = strconv.FormatInt(p, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(s, ".")))
+}
+
+func decodeCursor(cursor string, n int) ([]int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	fields := strings.Split(string(raw), ".")
+	if len(fields) != n {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	out := make([]int64, n)
+	for i, f := range fields {
+		if out[i], err = strconv.ParseInt(f, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	return out, nil
+}
+
+// getOnly rejects other methods; ok is false once the response is written.
+func getOnly(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func writePage(w http.ResponseWriter, p page) {
+	if p.Data == nil {
+		p.Data = []dto.FlatMarketData{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// handleTicks serves GET /v1/ticks?symbol=&from=&to=&limit=&cursor= from
+// the market data table, oldest first, shaped like /ws tick frames.
+func (s *Server) handleTicks(w http.ResponseWriter, r *http.Request) {
+	if !getOnly(w, r) {
+		return
+	}
+	_, cfg, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	h, err := parseHistory(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := storage.TickQuery{Range: storage.Range{Symbols: []string{h.symbol}, From: h.from, To: h.to}, Limit: h.limit}
+	if h.cursor != "" {
+		c, err := decodeCursor(h.cursor, 2)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.After = &storage.TickCursor{Timestamp: c[0], ID: c[1]}
+	}
+
+	ticks, next, err := s.store.QueryTicks(r.Context(), q)
+	if err != nil {
+		s.logger.Error("Failed to query ticks: ", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	var p page
+	for _, t := range ticks {
+		p.Data = append(p.Data, s.flatten(t, cfg))
+	}
+	if next != nil {
+		p.NextCursor = encodeCursor(next.Timestamp, next.ID)
+	}
+	writePage(w, p)
+}
+
+// handleBarHistory serves GET /v1/bars?symbol=&interval=&from=&to=&limit=&cursor=
+// with closed bars from the bars table, shaped like /ws bar frames.
+func (s *Server) handleBarHistory(w http.ResponseWriter, r *http.Request) {
+	if !getOnly(w, r) {
+		return
+	}
+	_, cfg, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	h, err := parseHistory(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	interval := r.URL.Query().Get("interval")
+	if _, ok := bars.Lookup(interval); !ok {
+		http.Error(w, fmt.Sprintf("invalid interval %q", interval), http.StatusBadRequest)
+		return
+	}
+	q := storage.CandleQuery{Symbol: h.symbol, Interval: interval, From: h.from, To: h.to, Limit: h.limit}
+	if h.cursor != "" {
+		c, err := decodeCursor(h.cursor, 1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.After = c[0]
+	}
+
+	candles, next, err := s.store.QueryCandles(r.Context(), q)
+	if err != nil {
+		s.logger.Error("Failed to query bars: ", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+	var p page
+	for i := range candles {
+		p.Data = append(p.Data, s.barFrame(&candles[i], cfg))
+	}
+	if next != 0 {
+		p.NextCursor = encodeCursor(next)
+	}
+	writePage(w, p)
+}
+
+// handleLatest serves GET /v1/latest?symbols=A,B with the newest tick of
+// each symbol from Redis, falling back to the market data table. Symbols
+// without any tick are left out.
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	if !getOnly(w, r) {
+		return
+	}
+	_, cfg, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	var symbols []string
+	for _, sym := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		if sym = strings.TrimSpace(sym); sym != "" {
+			symbols = append(symbols, sym)
+		}
+	}
+	if len(symbols) == 0 || len(symbols) > maxLatest {
+		http.Error(w, fmt.Sprintf("symbols must list 1 to %d symbols", maxLatest), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	var p page
+	for _, sym := range symbols {
+		tick, found, err := s.cache.Get(ctx, sym)
+		if err != nil || !found {
+			tick, found, err = s.store.LatestTick(ctx, sym)
+		}
+		if err != nil {
+			s.logger.Error("Failed to read latest tick: ", err)
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if found {
+			p.Data = append(p.Data, s.flatten(tick, cfg))
+		}
+	}
+	writePage(w, p)
+}
+
+// handleExport serves GET /v1/export, streaming every matching tick or bar
+// with the client's transforms; see export.ParseRequest for the parameters.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	if !getOnly(w, r) {
+		return
+	}
+	clientID, cfg, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	req, err := export.ParseRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", req.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+req.Filename()+`"`)
+	n, err := export.New(s.store, s.catalog).Run(r.Context(), req, cfg, w)
+	if err != nil {
+		// The status is already sent; abort so the client sees a broken
+		// transfer rather than a complete but short file
+		s.logger.Error(fmt.Sprintf("Export for %s failed after %d rows: %v", clientID, n, err))
+		panic(http.ErrAbortHandler)
+	}
+}
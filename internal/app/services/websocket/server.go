@@ -3,7 +3,6 @@ package websocket
 import (
 	"context"
 	"fmt"
-	"maps"
 	"net/http"
 	"sync"
 	"time"
@@ -11,6 +10,9 @@ import (
 	"ws_ingestor/internal/app/common/logger"
 	"ws_ingestor/internal/app/dto"
 	"ws_ingestor/internal/app/models"
+	"ws_ingestor/internal/app/services/instruments"
+	"ws_ingestor/internal/app/services/options"
+	"ws_ingestor/internal/app/services/replay"
 	"ws_ingestor/internal/app/services/storage"
 
 	"github.com/gorilla/websocket"
@@ -21,17 +23,26 @@ type Server struct {
 	addr     string
 	store    *storage.Store
 	cache    *storage.CacheService
+	catalog  *instruments.Catalog
+	chains   *options.Service
+	replays  *replay.Manager
 	logger   *logrus.Logger
 	upgrader websocket.Upgrader
 	clients  sync.Map // map[*websocket.Conn]bool
 }
 
-func NewServer(addr string, cache *storage.CacheService, store *storage.Store) *Server {
+// NewServer builds the client-facing server; chains (optional) enables
+// option chain subscriptions and replays (optional) lets clients join
+// replay sessions.
+func NewServer(addr string, cache *storage.CacheService, store *storage.Store, catalog *instruments.Catalog, chains *options.Service, replays *replay.Manager) *Server {
 	return &Server{
-		addr:   addr,
-		cache:  cache,
-		store:  store,
-		logger: logger.GetLogger(),
+		addr:    addr,
+		cache:   cache,
+		store:   store,
+		catalog: catalog,
+		chains:  chains,
+		replays: replays,
+		logger:  logger.GetLogger(),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins for demo
@@ -44,35 +55,55 @@ func (s *Server) Start(ctx context.Context) {
 	// Start broadcaster with stream reading
 	go s.broadcaster(ctx)
 
-	http.HandleFunc("/ws", s.handleConnection)
+	// Client endpoints get their own mux so the admin endpoints registered
+	// on http.DefaultServeMux are only served on the metrics port
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.handleConnection)
+	mux.HandleFunc("/v1/ticks", s.handleTicks)
+	mux.HandleFunc("/v1/bars", s.handleBarHistory)
+	mux.HandleFunc("/v1/latest", s.handleLatest)
+	mux.HandleFunc("/v1/export", s.handleExport)
 	s.logger.Info("Starting WebSocket server on " + s.addr)
-	if err := http.ListenAndServe(s.addr, nil); err != nil {
+	if err := http.ListenAndServe(s.addr, mux); err != nil {
 		s.logger.Fatal("Failed to start WebSocket server: ", err)
 	}
 }
 
-func (s *Server) handleConnection(w http.ResponseWriter, r *http.Request) {
+// authenticate resolves the client of the request's X-API-Key and its
+// config; on failure the error response is written and ok is false.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (clientID string, clientConfig *dto.ClientConfig, ok bool) {
 	ctx := r.Context()
 
 	apiKey := r.Header.Get("X-API-Key")
 	if apiKey == "" {
 		http.Error(w, "missing api key", http.StatusUnauthorized)
-		return
+		return "", nil, false
 	}
 
 	clientID, err := s.store.ValidateApiKey(ctx, apiKey)
 	if err != nil {
 		http.Error(w, "invalid api key", http.StatusUnauthorized)
-		return
+		return "", nil, false
 	}
 
-	clientConfig, err := s.store.GetClientConfig(ctx, clientID)
+	clientConfig, err = s.store.GetClientConfig(ctx, clientID)
 	if err != nil {
 		s.logger.Error("Failed to get client config: ", err)
 		http.Error(w, "server error", http.StatusInternalServerError)
+		return "", nil, false
+	}
+	return clientID, clientConfig, true
+}
+
+func (s *Server) handleConnection(w http.ResponseWriter, r *http.Request) {
+	clientID, clientConfig, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if id := r.URL.Query().Get("replay"); id != "" {
+		s.serveReplay(w, r, id, clientConfig)
 		return
 	}
-	fmt.Println("client config retrienved ============= ", clientConfig)
 
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -82,7 +113,7 @@ func (s *Server) handleConnection(w http.ResponseWriter, r *http.Request) {
 	client := s.getOrCreateClient(clientID, clientConfig)
 	client.addConn(conn)
 
-	go s.readPump(clientID, conn)
+	go s.readPump(client, conn)
 }
 
 func (s *Server) broadcaster(ctx context.Context) {
@@ -102,22 +133,42 @@ func (s *Server) broadcaster(ctx context.Context) {
 				continue
 			}
 
+			snapshots := &chainSnapshots{ctx: ctx, chains: s.chains, now: time.Now(), cache: make(map[string]*options.Chain)}
+			candles := &barSnapshots{ctx: ctx, cache: s.cache, seen: make(map[string][2]*models.Candle)}
+
 			// Send all data to connected clients
 			s.clients.Range(func(_, value interface{}) bool {
 				client := value.(*Client)
 				client.mu.Lock()
+			conns:
 				for conn := range client.conns {
 					for _, item := range allData {
-						flat := normalizeMarketData(item)
-						if client.Config != nil && client.Config.Symbols != nil {
-							if cfg, ok := client.Config.Symbols[item.Name]; ok {
-								flat = transformFlat(flat, &cfg)
-							}
+						if err := conn.WriteJSON(s.flatten(item, client.Config)); err != nil {
+							conn.Close()
+							delete(client.conns, conn)
+							continue conns
+						}
+					}
+					// Option chains the connection subscribed to
+					for _, req := range client.chains[conn] {
+						chain := snapshots.get(req)
+						if chain == nil {
+							continue
 						}
-						if err := conn.WriteJSON(flat); err != nil {
+						if err := conn.WriteJSON(s.chainFrame(chain, client.Config)); err != nil {
 							conn.Close()
 							delete(client.conns, conn)
-							break
+							continue conns
+						}
+					}
+					// Bar streams: each closed bar once, then the bar in progress
+					for _, w := range client.bars[conn] {
+						for _, candle := range w.next(candles.get(w.symbol, w.interval)) {
+							if err := conn.WriteJSON(s.barFrame(candle, client.Config)); err != nil {
+								conn.Close()
+								delete(client.conns, conn)
+								continue conns
+							}
 						}
 					}
 				}
@@ -138,6 +189,8 @@ func (s *Server) getOrCreateClient(clientID string, clientConfig *dto.ClientConf
 	client := &Client{
 		ID:     clientID,
 		conns:  make(map[*websocket.Conn]struct{}),
+		chains: make(map[*websocket.Conn]map[string]options.Request),
+		bars:   make(map[*websocket.Conn]map[string]*barWatch),
 		Config: clientConfig,
 	}
 
@@ -145,21 +198,19 @@ func (s *Server) getOrCreateClient(clientID string, clientConfig *dto.ClientConf
 	return actual.(*Client)
 }
 
-func (s *Server) readPump(clientID string, conn *websocket.Conn) {
+func (s *Server) readPump(client *Client, conn *websocket.Conn) {
 	defer func() {
 		conn.Close()
 
-		if val, ok := s.clients.Load(clientID); ok {
-			client := val.(*Client)
-			client.removeConn(conn)
-
-			if client.isEmpty() {
-				s.clients.Delete(clientID)
-			}
+		for _, req := range client.removeConn(conn) {
+			s.chains.Unwatch(req)
+		}
+		if client.isEmpty() {
+			s.clients.Delete(client.ID)
 		}
 	}()
 
-	conn.SetReadLimit(512)
+	conn.SetReadLimit(4096)
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	conn.SetPongHandler(func(string) error {
 		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -167,72 +218,10 @@ func (s *Server) readPump(clientID string, conn *websocket.Conn) {
 	})
 
 	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
 			return
 		}
+		s.handleMessage(client, conn, msg)
 	}
 }
-
-func normalizeMarketData(item models.MarketData) dto.FlatMarketData {
-	out := dto.FlatMarketData{}
-
-	// Flatten data block
-	if inner, ok := item.Data["data"].(map[string]interface{}); ok {
-		maps.Copy(out, inner)
-	}
-	out["symbol"] = item.Name
-	out["timestamp"] = item.Timestamp
-	out["exchange"] = item.Exchange
-
-	return out
-}
-
-func transformFlat(data dto.FlatMarketData, cfg *dto.SymbolConfig) dto.FlatMarketData {
-
-	// Value transforms
-	for field, rule := range cfg.ValueRules {
-		if v, ok := data[field].(float64); ok {
-			data[field] = applyValueRule(v, rule)
-		}
-	}
-
-	// Rename fields
-	for oldKey, newKey := range cfg.RenameFields {
-		if v, ok := data[oldKey]; ok {
-			data[newKey] = v
-			delete(data, oldKey)
-		}
-	}
-
-	// Remove fields
-	for _, field := range cfg.RemoveFields {
-		delete(data, field)
-	}
-
-	// Hard overrides
-	for k, v := range cfg.OverrideFields {
-		if k == "timestamp" && v == "current" {
-			data[k] = time.Now().UnixMilli()
-		} else {
-			data[k] = v
-		}
-	}
-
-	return data
-}
-
-func applyValueRule(num float64, rule dto.ValueRule) float64 {
-	switch rule.Op {
-	case "add":
-		return num + rule.Value
-	case "subtract":
-		return num - rule.Value
-	case "multiply":
-		return num * rule.Value
-	case "divide":
-		if rule.Value != 0 {
-			return num / rule.Value
-		}
-	}
-	return num
-}
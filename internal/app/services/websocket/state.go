@@ -0,0 +1,61 @@
+package websocket
+
+import "ws_ingestor/internal/app/metrics"
+
+// ConnState is the lifecycle state of an upstream feed connection.
+type ConnState int
+
+const (
+	// StateConnecting: dialing or waiting to redial.
+	StateConnecting ConnState = iota
+	// StateSubscribed: connected and the subscription set has been sent.
+	StateSubscribed
+	// StateDegraded: the watchdog detected a stall or the last session ended
+	// before it was stable; reconnecting.
+	StateDegraded
+	// StateDown: consecutive dial failures reached the failure threshold;
+	// the circuit is open and retries continue at the maximum backoff
+	// (jittered) until a stable session resets it.
+	StateDown
+)
+
+var connStates = []ConnState{StateConnecting, StateSubscribed, StateDegraded, StateDown}
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateSubscribed:
+		return "subscribed"
+	case StateDegraded:
+		return "degraded"
+	case StateDown:
+		return "down"
+	}
+	return "unknown"
+}
+
+func (c *Ingestor) setState(s ConnState) {
+	c.mu.Lock()
+	prev := c.state
+	c.state = s
+	c.mu.Unlock()
+
+	if prev != s {
+		c.logger.Info("Feed " + c.name + " state " + prev.String() + " -> " + s.String())
+	}
+	for _, st := range connStates {
+		v := 0.0
+		if st == s {
+			v = 1
+		}
+		metrics.FeedState.WithLabelValues(c.name, st.String()).Set(v)
+	}
+}
+
+// State returns the current connection state.
+func (c *Ingestor) State() ConnState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
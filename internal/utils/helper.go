@@ -3,9 +3,27 @@ package utils
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"strconv"
+	"time"
 )
 
 func HashAPIKey(apiKey string) string {
 	sum := sha256.Sum256([]byte(apiKey))
 	return hex.EncodeToString(sum[:])
 }
+
+// ParseMillis reads a time given as Unix milliseconds or RFC 3339 and
+// returns it in Unix milliseconds; empty input yields zero.
+func ParseMillis(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}